@@ -0,0 +1,511 @@
+// Copyright (C) 2022
+// Author FrankXu <frankxury@gmail.com>
+// Build on 2026/10/18
+
+// The batch engine shared by the cross slot commands.
+
+package redis
+
+import (
+	"context"
+	goredis "github.com/go-redis/redis/v8"
+	"sync"
+)
+
+const (
+	MAX_REDIRECT_TIMES = 3
+)
+
+// batchCall is one queued command and the keys it covers.
+type batchCall struct {
+	Keys []string
+	Cmd  goredis.Cmder
+	Addr string // the node which the command was sent to.
+}
+
+// batchSpec describes how a batch command is queued on one node.
+// The queue function must emit exactly one command per returned call.
+type batchSpec struct {
+	isWrite bool
+	queue   func(ctx context.Context, pipe goredis.Pipeliner, keys []string) []*batchCall
+
+	// the payload size of the key for the chunking, the key length by default.
+	sizeOf func(key string) int
+
+	// the values of the keys of a call, by default the call covers one key
+	// and the value is taken from its command.
+	parse func(call *batchCall) []interface{}
+
+	// called once for every call which succeeded, to aggregate the replies
+	// of the multi-key commands, like the count of a DEL.
+	onCall func(call *batchCall)
+}
+
+// batchTask is a group of keys to be sent to one node.
+type batchTask struct {
+	keys     []string
+	group    *redisGroup // the group routed by the slot table.
+	addr     string      // the ASK target, the keys are sent with ASKING.
+	isPerKey bool        // to queue the keys one by one, like after a TRYAGAIN.
+}
+
+type batchTaskResult struct {
+	task  *batchTask
+	calls []*batchCall
+	err   error
+}
+
+// to queue one multi-key command per slot, the keys of every slot are kept
+// in their order.
+func queuePerSlot(fn func(ctx context.Context, pipe goredis.Pipeliner, keys []string) goredis.Cmder) func(context.Context, goredis.Pipeliner, []string) []*batchCall {
+	return func(ctx context.Context, pipe goredis.Pipeliner, keys []string) []*batchCall {
+		crc16Handle := NewCRC16()
+		slots := []uint16{}
+		slotKeysMap := map[uint16][]string{}
+		for _, key := range keys {
+			slot := crc16Handle.HashSlot(key)
+			if _, isExists := slotKeysMap[slot]; !isExists {
+				slots = append(slots, slot)
+			}
+			slotKeysMap[slot] = append(slotKeysMap[slot], key)
+		}
+
+		calls := make([]*batchCall, 0, len(slots))
+		for _, slot := range slots {
+			calls = append(calls, &batchCall{Keys: slotKeysMap[slot], Cmd: fn(ctx, pipe, slotKeysMap[slot])})
+		}
+		return calls
+	}
+}
+
+// a duplicated key is counted as many times as given by EXISTS and TOUCH, so
+// it is repeated in the command of its slot.
+func queuePerSlotRepeated(keys []string, fn func(ctx context.Context, pipe goredis.Pipeliner, keys []string) goredis.Cmder) func(context.Context, goredis.Pipeliner, []string) []*batchCall {
+	keyTimesMap := map[string]int{}
+	for _, key := range keys {
+		keyTimesMap[key]++
+	}
+
+	return queuePerSlot(func(ctx context.Context, pipe goredis.Pipeliner, keys []string) goredis.Cmder {
+		args := []string{}
+		for _, key := range keys {
+			for i := 0; i < keyTimesMap[key]; i++ {
+				args = append(args, key)
+			}
+		}
+		return fn(ctx, pipe, args)
+	})
+}
+
+// to queue one command per key.
+func queuePerKey(fn func(ctx context.Context, pipe goredis.Pipeliner, key string) goredis.Cmder) func(context.Context, goredis.Pipeliner, []string) []*batchCall {
+	return func(ctx context.Context, pipe goredis.Pipeliner, keys []string) []*batchCall {
+		calls := make([]*batchCall, 0, len(keys))
+		for _, key := range keys {
+			calls = append(calls, &batchCall{Keys: []string{key}, Cmd: fn(ctx, pipe, key)})
+		}
+		return calls
+	}
+}
+
+func (this *RedisCluster) getBatchTasks(keys []string) ([]*batchTask, []string) {
+	tasks := []*batchTask{}
+	keyNodesMap, missedKeys := this.getKeyNodesMap(keys)
+	for _, item := range keyNodesMap {
+		tasks = append(tasks, &batchTask{keys: item.Keys, group: item.HitNodeGP})
+	}
+	return tasks, missedKeys
+}
+
+// runBatch sends every distinct key once to its node and records the outcome
+// per key. ASK redirections only resend the affected keys to the target node,
+// MOVED redirections patch the slot table and reroute the moved keys while a
+// full refresh is scheduled in the background, the keys which have succeeded
+// are never executed twice.
+func (this *RedisCluster) runBatch(ctx context.Context, keys []string, spec *batchSpec) *BatchResult {
+	helper := NewRedisHelper()
+	result := newBatchResult(keys)
+	tasks, missedKeys := this.getBatchTasks(result.UniqueKeys())
+	result.setKeysErr(missedKeys, ErrSlotNotServed)
+
+	for triedTimes := 0; len(tasks) > 0; triedTimes++ {
+		askMap := map[string]*batchTask{}
+		movedSlots := map[uint16]string{}
+		redirectKeys, movedKeys := []string{}, []string{}
+		tryAgainKeys := []string{}
+		var redirectErr error
+
+		taskResults, err := this.execBatchTasks(ctx, tasks, spec)
+		finishedMap := map[*batchTask]bool{}
+
+		for _, taskRes := range taskResults {
+			finishedMap[taskRes.task] = true
+			resolvedMap := map[string]bool{}
+
+			for _, call := range taskRes.calls {
+				for _, key := range call.Keys {
+					resolvedMap[key] = true
+				}
+
+				// a multi-key command on a slot in migration, the keys are
+				// retried one by one to follow their own ASK.
+				if helper.IsTryAgainError(call.Cmd.Err()) && len(call.Keys) > 1 {
+					tryAgainKeys = append(tryAgainKeys, call.Keys...)
+					redirectKeys = append(redirectKeys, call.Keys...)
+					redirectErr = call.Cmd.Err()
+					continue
+				}
+
+				redirect, isRedirect := helper.ParseRedirectError(call.Cmd.Err())
+				if !isRedirect {
+					this.setCallResult(result, call, spec)
+					continue
+				}
+
+				switch redirect.Kind {
+				case REDIRECT_ASK:
+					if _, isExists := askMap[redirect.Addr]; !isExists {
+						askMap[redirect.Addr] = &batchTask{addr: redirect.Addr}
+					}
+					askMap[redirect.Addr].keys = append(askMap[redirect.Addr].keys, call.Keys...)
+				case REDIRECT_MOVED:
+					movedSlots[redirect.Slot] = redirect.Addr
+					movedKeys = append(movedKeys, call.Keys...)
+				}
+				redirectKeys = append(redirectKeys, call.Keys...)
+				redirectErr = call.Cmd.Err()
+			}
+
+			// the keys never sent, like the chunks after a cancellation.
+			if taskRes.err != nil {
+				for _, key := range taskRes.task.keys {
+					if !resolvedMap[key] {
+						result.setKeysErr([]string{key}, taskRes.err)
+					}
+				}
+			}
+		}
+
+		// cancelled, or failed fast: the unfinished tasks take the error.
+		if err != nil {
+			for _, task := range tasks {
+				if !finishedMap[task] {
+					result.setKeysErr(task.keys, err)
+				}
+			}
+			result.setKeysErr(redirectKeys, err)
+			break
+		}
+
+		if redirectErr == nil {
+			break
+		}
+		if triedTimes >= MAX_REDIRECT_TIMES {
+			result.setKeysErr(redirectKeys, redirectErr)
+			break
+		}
+
+		tasks = []*batchTask{}
+		for _, task := range askMap {
+			tasks = append(tasks, task)
+		}
+		if len(tryAgainKeys) > 0 {
+			tryAgainTasks, missedKeys := this.getBatchTasks(tryAgainKeys)
+			for _, task := range tryAgainTasks {
+				task.isPerKey = true
+			}
+			tasks = append(tasks, tryAgainTasks...)
+			result.setKeysErr(missedKeys, ErrSlotNotServed)
+		}
+		if len(movedKeys) > 0 {
+			this.nodes.PatchSlots(movedSlots)
+			this.scheduleRefresh()
+
+			movedTasks, missedKeys := this.getBatchTasks(movedKeys)
+			tasks = append(tasks, movedTasks...)
+			result.setKeysErr(missedKeys, ErrSlotNotServed)
+		}
+	}
+
+	return result
+}
+
+// to record the value or the error of the keys of one call, a miss is
+// neither a value nor an error.
+func (this *RedisCluster) setCallResult(result *BatchResult, call *batchCall, spec *batchSpec) {
+	err := call.Cmd.Err()
+	if err == goredis.Nil {
+		err = nil
+	}
+
+	vals := []interface{}{}
+	if err == nil {
+		if spec.parse != nil {
+			vals = spec.parse(call)
+		} else {
+			vals = append(vals, cmdVal(call.Cmd))
+		}
+	}
+
+	if err == nil && spec.onCall != nil {
+		spec.onCall(call)
+	}
+
+	for i, key := range call.Keys {
+		keyRes := result.Get(key)
+		if keyRes == nil {
+			continue
+		}
+		keyRes.Err, keyRes.Addr, keyRes.Val = err, call.Addr, nil
+		if i < len(vals) {
+			keyRes.Val = vals[i]
+		}
+	}
+}
+
+// to get the value of a finished command, nil for a miss.
+func cmdVal(cmd goredis.Cmder) interface{} {
+	if cmd.Err() != nil {
+		return nil
+	}
+
+	switch one := cmd.(type) {
+	case *goredis.IntCmd:
+		return one.Val()
+	case *goredis.StatusCmd:
+		return one.Val()
+	case *goredis.StringCmd:
+		return one.Val()
+	case *goredis.BoolCmd:
+		return one.Val()
+	case *goredis.FloatCmd:
+		return one.Val()
+	case *goredis.DurationCmd:
+		return one.Val()
+	case *goredis.SliceCmd:
+		return one.Val()
+	case *goredis.StringSliceCmd:
+		return one.Val()
+	case *goredis.IntSliceCmd:
+		return one.Val()
+	case *goredis.StringStringMapCmd:
+		return one.Val()
+	case *goredis.ZSliceCmd:
+		return one.Val()
+	case *goredis.Cmd:
+		return one.Val()
+	}
+	return nil
+}
+
+// to run every task by one pipeline, at most MaxConcurrency at a time. The
+// wait returns as soon as the ctx is done, and with FailFast the tasks in
+// flight are cancelled on the first hard error.
+func (this *RedisCluster) execBatchTasks(ctx context.Context, tasks []*batchTask, spec *batchSpec) ([]*batchTaskResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	opts := getBatchOptions(ctx)
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	resCh := make(chan *batchTaskResult, len(tasks))
+	workerCh := make(chan struct{}, this.getMaxConcurrency(opts))
+
+	go func() {
+		for _, task := range tasks {
+			select {
+			case workerCh <- struct{}{}:
+			case <-runCtx.Done():
+				resCh <- &batchTaskResult{task: task, err: runCtx.Err()}
+				continue
+			}
+
+			go func(resCh chan *batchTaskResult, curTask *batchTask) {
+				defer func() { <-workerCh }()
+				resCh <- this.execBatchTask(runCtx, curTask, spec)
+			}(resCh, task)
+		}
+	}()
+
+	results := make([]*batchTaskResult, 0, len(tasks))
+	for i := 0; i < len(tasks); i++ {
+		select {
+		case curRes := <-resCh:
+			results = append(results, curRes)
+			if opts.FailFast {
+				if err := curRes.hardError(); err != nil {
+					return results, err
+				}
+			}
+		case <-ctx.Done():
+			return results, ctx.Err()
+		}
+	}
+
+	return results, nil
+}
+
+func (this *RedisCluster) getMaxConcurrency(opts *BatchOptions) int {
+	if opts.MaxConcurrency > 0 {
+		return opts.MaxConcurrency
+	}
+	if this.extOpts.MaxConcurrency > 0 {
+		return this.extOpts.MaxConcurrency
+	}
+	return MAX_COUCUR
+}
+
+// to get the first error which is neither a miss nor a redirection, the
+// TRYAGAIN of a multi-key command is retried per key so it is not hard either.
+func (this *batchTaskResult) hardError() error {
+	if this.err != nil {
+		return this.err
+	}
+
+	helper := NewRedisHelper()
+	for _, call := range this.calls {
+		err := call.Cmd.Err()
+		if err == nil || err == goredis.Nil {
+			continue
+		}
+		if helper.IsTryAgainError(err) && len(call.Keys) > 1 {
+			continue
+		}
+		if _, isRedirect := helper.ParseRedirectError(err); !isRedirect {
+			return err
+		}
+	}
+	return nil
+}
+
+func (this *RedisCluster) execBatchTask(ctx context.Context, task *batchTask, spec *batchSpec) *batchTaskResult {
+	curRes := &batchTaskResult{task: task}
+
+	var curClient *RedisClient
+	if task.addr != "" {
+		curClient, curRes.err = this.clientFactory.GetRedisClientByAddr(task.addr)
+	} else if spec.isWrite {
+		curClient, curRes.err = this.clientFactory.GetRedisClientByPolicy(task.group, READ_FROM_MASTER)
+	} else {
+		curClient, curRes.err = this.clientFactory.GetRedisClientByPolicy(task.group, this.getReadPolicy(ctx))
+	}
+	if curRes.err != nil {
+		return curRes
+	}
+
+	opts := getBatchOptions(ctx)
+	chunks := this.splitChunks(task.keys, opts, spec)
+	if len(chunks) == 1 {
+		curRes.calls = this.execBatchChunk(ctx, curClient, task, chunks[0], spec)
+		return curRes
+	}
+
+	// to send the chunks by ChunkConcurrency pipelines at a time, the calls
+	// are merged in the chunk order.
+	chunkCalls := make([][]*batchCall, len(chunks))
+	workerCh := make(chan struct{}, this.getChunkConcurrency(opts))
+	wg := sync.WaitGroup{}
+	for i, chunk := range chunks {
+		select {
+		case workerCh <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			curRes.err = ctx.Err()
+			break
+		}
+
+		wg.Add(1)
+		go func(idx int, curChunk []string) {
+			defer func() {
+				<-workerCh
+				wg.Done()
+			}()
+			chunkCalls[idx] = this.execBatchChunk(ctx, curClient, task, curChunk, spec)
+		}(i, chunk)
+	}
+	wg.Wait()
+
+	for _, calls := range chunkCalls {
+		curRes.calls = append(curRes.calls, calls...)
+	}
+
+	return curRes
+}
+
+// to send the keys of one chunk by one pipeline.
+func (this *RedisCluster) execBatchChunk(ctx context.Context, curClient *RedisClient, task *batchTask, keys []string, spec *batchSpec) []*batchCall {
+	calls := []*batchCall{}
+	curPipe := curClient.Pipeline()
+	if task.addr == "" && !task.isPerKey {
+		calls = spec.queue(ctx, curPipe, keys)
+	} else {
+		// ASKING only covers the next command, so it is sent before every key.
+		for _, key := range keys {
+			if task.addr != "" {
+				curPipe.Process(ctx, goredis.NewStatusCmd(ctx, "asking"))
+			}
+			calls = append(calls, spec.queue(ctx, curPipe, []string{key})...)
+		}
+	}
+
+	// the errors are checked per command by the caller.
+	curPipe.Exec(ctx)
+
+	addr := curClient.Options().Addr
+	for _, call := range calls {
+		call.Addr = addr
+	}
+
+	return calls
+}
+
+// splitChunks splits the keys of one node by MaxKeysPerPipeline and
+// MaxBytesPerPipeline, every chunk holds one key at least.
+func (this *RedisCluster) splitChunks(keys []string, opts *BatchOptions, spec *batchSpec) [][]string {
+	maxKeys, maxBytes := opts.MaxKeysPerPipeline, opts.MaxBytesPerPipeline
+	if maxKeys <= 0 {
+		maxKeys = this.extOpts.MaxKeysPerPipeline
+	}
+	if maxBytes <= 0 {
+		maxBytes = this.extOpts.MaxBytesPerPipeline
+	}
+	if maxKeys <= 0 && maxBytes <= 0 {
+		return [][]string{keys}
+	}
+
+	chunks := [][]string{}
+	chunk := []string{}
+	chunkBytes := 0
+	for _, key := range keys {
+		keyBytes := len(key)
+		if spec.sizeOf != nil {
+			keyBytes = spec.sizeOf(key)
+		}
+
+		isKeysFull := maxKeys > 0 && len(chunk) >= maxKeys
+		isBytesFull := maxBytes > 0 && chunkBytes+keyBytes > maxBytes
+		if len(chunk) > 0 && (isKeysFull || isBytesFull) {
+			chunks = append(chunks, chunk)
+			chunk = []string{}
+			chunkBytes = 0
+		}
+
+		chunk = append(chunk, key)
+		chunkBytes += keyBytes
+	}
+	if len(chunk) > 0 || len(chunks) == 0 {
+		chunks = append(chunks, chunk)
+	}
+
+	return chunks
+}
+
+func (this *RedisCluster) getChunkConcurrency(opts *BatchOptions) int {
+	if opts.ChunkConcurrency > 0 {
+		return opts.ChunkConcurrency
+	}
+	return 1
+}
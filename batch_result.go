@@ -0,0 +1,117 @@
+// Copyright (C) 2022
+// Author FrankXu <frankxury@gmail.com>
+// Build on 2026/10/18
+
+// The per key results of the batch commands.
+
+package redis
+
+import (
+	"errors"
+)
+
+var (
+	ErrSlotNotServed = errors.New("no node serves the slot of the key.")
+)
+
+// KeyResult is the outcome of one key in a batch command, a missing key of
+// a read has a nil Val and a nil Err.
+type KeyResult struct {
+	Key  string
+	Val  interface{}
+	Err  error
+	Addr string // the node which served the key, empty when it was not sent.
+}
+
+// BatchResult records the outcome of every key of a batch command, so the
+// failed keys can be retried alone.
+type BatchResult struct {
+	// in the order of the keys given, a duplicated key shares one result.
+	Results []*KeyResult
+
+	keyMap map[string]*KeyResult
+	err    error // the error of the whole batch, like a bad argument.
+}
+
+func newBatchResult(keys []string) *BatchResult {
+	result := &BatchResult{
+		Results: make([]*KeyResult, 0, len(keys)),
+		keyMap:  make(map[string]*KeyResult, len(keys)),
+	}
+	for _, key := range keys {
+		keyRes, isExists := result.keyMap[key]
+		if !isExists {
+			keyRes = &KeyResult{Key: key}
+			result.keyMap[key] = keyRes
+		}
+		result.Results = append(result.Results, keyRes)
+	}
+	return result
+}
+
+func newBatchErrResult(err error) *BatchResult {
+	result := newBatchResult([]string{})
+	result.err = err
+	return result
+}
+
+// UniqueKeys returns the keys without the duplicates, in the order given.
+func (this *BatchResult) UniqueKeys() []string {
+	keys := make([]string, 0, len(this.keyMap))
+	seenMap := make(map[string]bool, len(this.keyMap))
+	for _, one := range this.Results {
+		if !seenMap[one.Key] {
+			seenMap[one.Key] = true
+			keys = append(keys, one.Key)
+		}
+	}
+	return keys
+}
+
+// Get returns the result of the key, nil when the key is not in the batch.
+func (this *BatchResult) Get(key string) *KeyResult {
+	return this.keyMap[key]
+}
+
+// Err returns the error of the whole batch, or the first error in the order of the keys.
+func (this *BatchResult) Err() error {
+	if this.err != nil {
+		return this.err
+	}
+	for _, one := range this.Results {
+		if one.Err != nil {
+			return one.Err
+		}
+	}
+	return nil
+}
+
+// FailedKeys returns the keys which failed, without the duplicates.
+func (this *BatchResult) FailedKeys() []string {
+	keys := []string{}
+	for _, key := range this.UniqueKeys() {
+		if this.keyMap[key].Err != nil {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// SucceededKeys returns the keys which succeeded, without the duplicates.
+func (this *BatchResult) SucceededKeys() []string {
+	keys := []string{}
+	for _, key := range this.UniqueKeys() {
+		if this.keyMap[key].Err == nil {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+func (this *BatchResult) setKeysErr(keys []string, err error) {
+	for _, key := range keys {
+		if keyRes := this.keyMap[key]; keyRes != nil {
+			keyRes.Err = err
+		}
+	}
+}
@@ -0,0 +1,111 @@
+// Copyright (C) 2022
+// Author FrankXu <frankxury@gmail.com>
+// Build on 2026/10/18
+
+// The batch results test.
+
+package redis
+
+import (
+	"context"
+	"errors"
+	goredis "github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestBatchResult(t *testing.T) {
+	assert := assert.New(t)
+	result := newBatchResult([]string{"a", "b", "c", "b"})
+	assert.Equal([]string{"a", "b", "c"}, result.UniqueKeys())
+	assert.Nil(result.Err())
+
+	failErr := errors.New("LOADING")
+	result.setKeysErr([]string{"c"}, failErr)
+	result.Get("a").Val = int64(1)
+
+	assert.Equal(failErr, result.Err())
+	assert.Equal([]string{"c"}, result.FailedKeys())
+	assert.Equal([]string{"a", "b"}, result.SucceededKeys())
+	assert.Equal(int64(1), result.Results[0].Val)
+
+	result = newBatchErrResult(failErr)
+	assert.Equal(failErr, result.Err())
+	assert.Len(result.Results, 0)
+}
+
+func TestSetCallResult(t *testing.T) {
+	assert := assert.New(t)
+	cluster := newTestCluster(t, nil)
+	result := newBatchResult([]string{"a", "b", "c"})
+	ctx := context.Background()
+
+	hitCmd := goredis.NewStringCmd(ctx, "get", "a")
+	hitCmd.SetVal("val-a")
+	missCmd := goredis.NewStringCmd(ctx, "get", "b")
+	missCmd.SetErr(goredis.Nil)
+	failCmd := goredis.NewStringCmd(ctx, "get", "c")
+	failCmd.SetErr(errors.New("LOADING"))
+
+	spec := &batchSpec{}
+	cluster.setCallResult(result, &batchCall{Keys: []string{"a"}, Cmd: hitCmd, Addr: "10.0.0.1:6379"}, spec)
+	cluster.setCallResult(result, &batchCall{Keys: []string{"b"}, Cmd: missCmd, Addr: "10.0.0.1:6379"}, spec)
+	cluster.setCallResult(result, &batchCall{Keys: []string{"c"}, Cmd: failCmd, Addr: "10.0.0.2:6379"}, spec)
+
+	assert.Equal(&KeyResult{Key: "a", Val: "val-a", Addr: "10.0.0.1:6379"}, result.Get("a"))
+	assert.Equal(&KeyResult{Key: "b", Addr: "10.0.0.1:6379"}, result.Get("b"))
+	assert.Equal("LOADING", result.Get("c").Err.Error())
+	assert.Nil(result.Get("c").Val)
+}
+
+func TestSetSlotCallResult(t *testing.T) {
+	assert := assert.New(t)
+	cluster := newTestCluster(t, nil)
+	result := newBatchResult([]string{"{u}a", "{u}b"})
+	ctx := context.Background()
+
+	var totalVal int
+	mgetCmd := goredis.NewSliceCmd(ctx, "mget", "{u}a", "{u}b")
+	mgetCmd.SetVal([]interface{}{"val-a", nil})
+	cluster.setCallResult(result, &batchCall{Keys: []string{"{u}a", "{u}b"}, Cmd: mgetCmd}, &batchSpec{
+		parse: func(call *batchCall) []interface{} {
+			return call.Cmd.(*goredis.SliceCmd).Val()
+		},
+		onCall: func(call *batchCall) {
+			totalVal++
+		},
+	})
+
+	assert.Equal("val-a", result.Get("{u}a").Val)
+	assert.Nil(result.Get("{u}b").Val)
+	assert.Nil(result.Err())
+	assert.Equal(1, totalVal)
+}
+
+func TestToMGetCmd(t *testing.T) {
+	assert := assert.New(t)
+	cluster := newTestCluster(t, nil)
+	ctx := context.Background()
+	keys := []string{"t0", "t1", "t2"}
+
+	result := newBatchResult(keys)
+	result.Get("t0").Val = "val-0"
+	result.Get("t2").Val = "val-2"
+
+	type data struct {
+		T0 string `redis:"t0"`
+		T1 string `redis:"t1"`
+		T2 string `redis:"t2"`
+	}
+	var d data
+	cmd := cluster.toMGetCmd(ctx, keys, result)
+	assert.Nil(cmd.Err())
+	assert.Equal([]interface{}{"val-0", nil, "val-2"}, cmd.Val())
+	assert.Nil(cmd.Scan(&d))
+	assert.Equal(data{"val-0", "", "val-2"}, d)
+
+	result.setKeysErr([]string{"t1"}, ErrSlotNotServed)
+	cmd = cluster.toMGetCmd(ctx, keys, result)
+	assert.Equal(ErrSlotNotServed, cmd.Err())
+	assert.Equal([]interface{}{"val-0", ErrSlotNotServed, "val-2"}, cmd.Val())
+}
@@ -0,0 +1,341 @@
+// Copyright (C) 2022
+// Author FrankXu <frankxury@gmail.com>
+// Build on 2026/10/18
+
+// The batch engine test.
+
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	goredis "github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newTestCluster news a cluster object without any connection, its masters
+// are marked as failed so the tasks fail before dialing.
+func newTestCluster(t *testing.T, ext *ExtOptions) *RedisCluster {
+	nodes, err := NewRedisNodes("aaaa 10.0.0.1:6379@16379 master,fail - 0 0 1 connected 0-8191\n" +
+		"bbbb 10.0.0.2:6379@16379 master,fail - 0 0 2 connected 8192-16383\n")
+	assert.Nil(t, err)
+
+	return &RedisCluster{
+		nodes:         nodes,
+		clientFactory: NewRedisClientFactory(&goredis.ClusterOptions{}),
+		curContext:    context.Background(),
+		extOpts:       ext.init(),
+		notifier:      newTopologyNotifier(),
+		closeCh:       make(chan struct{}),
+	}
+}
+
+func TestGetMaxConcurrency(t *testing.T) {
+	assert := assert.New(t)
+	cluster := newTestCluster(t, nil)
+	assert.Equal(MAX_COUCUR, cluster.getMaxConcurrency(&BatchOptions{}))
+
+	cluster = newTestCluster(t, &ExtOptions{MaxConcurrency: 2})
+	assert.Equal(2, cluster.getMaxConcurrency(&BatchOptions{}))
+	assert.Equal(5, cluster.getMaxConcurrency(&BatchOptions{MaxConcurrency: 5}))
+}
+
+func TestExecBatchTasks(t *testing.T) {
+	assert := assert.New(t)
+	cluster := newTestCluster(t, &ExtOptions{MaxConcurrency: 1})
+	spec := &batchSpec{isWrite: true, queue: queuePerKey(func(ctx context.Context, pipe goredis.Pipeliner, key string) goredis.Cmder {
+		return pipe.Get(ctx, key)
+	})}
+	tasks, missedKeys := cluster.getBatchTasks([]string{"a", "b", "c", "d"})
+	assert.Len(tasks, 2)
+	assert.Len(missedKeys, 0)
+
+	results, err := cluster.execBatchTasks(context.Background(), tasks, spec)
+	assert.Nil(err)
+	assert.Len(results, 2)
+	for _, one := range results {
+		assert.NotNil(one.hardError())
+	}
+
+	ctx := WithBatchOptions(context.Background(), &BatchOptions{FailFast: true})
+	results, err = cluster.execBatchTasks(ctx, tasks, spec)
+	assert.NotNil(err)
+	assert.Len(results, 1)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = cluster.execBatchTasks(ctx, tasks, spec)
+	assert.NotNil(err)
+}
+
+func TestHardError(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	newCall := func(err error, keys ...string) *batchCall {
+		cmd := goredis.NewStringCmd(ctx, "get")
+		cmd.SetErr(err)
+		return &batchCall{Keys: keys, Cmd: cmd}
+	}
+	tryAgainErr := errors.New("TRYAGAIN Multiple keys request during rehashing of slot")
+
+	// the misses, the redirections and the TRYAGAIN of the multi-key commands are retried.
+	taskRes := &batchTaskResult{calls: []*batchCall{
+		newCall(nil, "a"),
+		newCall(goredis.Nil, "b"),
+		newCall(errors.New("MOVED 3300 127.0.0.1:7001"), "c"),
+		newCall(errors.New("ASK 3300 127.0.0.1:7001"), "d"),
+		newCall(tryAgainErr, "e", "f"),
+	}}
+	assert.Nil(taskRes.hardError())
+
+	// the TRYAGAIN of one key is not retried again.
+	taskRes.calls = append(taskRes.calls, newCall(tryAgainErr, "g"))
+	assert.Equal(tryAgainErr, taskRes.hardError())
+
+	taskRes = &batchTaskResult{err: ErrSlotNotServed}
+	assert.Equal(ErrSlotNotServed, taskRes.hardError())
+}
+
+func TestSplitChunks(t *testing.T) {
+	keys := []string{"a", "bb", "ccc", "dddd", "e"}
+	testCases := []struct {
+		Opts   BatchOptions
+		Ext    ExtOptions
+		Chunks [][]string
+	}{
+		{BatchOptions{}, ExtOptions{}, [][]string{keys}},
+		{BatchOptions{MaxKeysPerPipeline: 2}, ExtOptions{}, [][]string{{"a", "bb"}, {"ccc", "dddd"}, {"e"}}},
+		{BatchOptions{}, ExtOptions{MaxKeysPerPipeline: 3}, [][]string{{"a", "bb", "ccc"}, {"dddd", "e"}}},
+		{BatchOptions{MaxBytesPerPipeline: 4}, ExtOptions{}, [][]string{{"a", "bb"}, {"ccc"}, {"dddd"}, {"e"}}},
+		{BatchOptions{MaxBytesPerPipeline: 2}, ExtOptions{}, [][]string{{"a"}, {"bb"}, {"ccc"}, {"dddd"}, {"e"}}},
+		{BatchOptions{MaxKeysPerPipeline: 2, MaxBytesPerPipeline: 6}, ExtOptions{}, [][]string{{"a", "bb"}, {"ccc"}, {"dddd", "e"}}},
+	}
+
+	assert := assert.New(t)
+	spec := &batchSpec{}
+	for _, test := range testCases {
+		ext := test.Ext
+		cluster := newTestCluster(t, &ext)
+		opts := test.Opts
+		assert.Equal(test.Chunks, cluster.splitChunks(keys, &opts, spec), "%#v %#v failed.", test.Opts, test.Ext)
+	}
+
+	// the payload counts the values.
+	cluster := newTestCluster(t, nil)
+	spec.sizeOf = func(key string) int { return len(key) + 10 }
+	assert.Equal([][]string{{"a", "bb"}, {"ccc"}, {"dddd"}, {"e"}},
+		cluster.splitChunks(keys, &BatchOptions{MaxBytesPerPipeline: 24}, spec))
+}
+
+func TestSizeOf(t *testing.T) {
+	assert := assert.New(t)
+	helper := NewRedisHelper()
+	assert.Equal(0, helper.SizeOf(nil))
+	assert.Equal(3, helper.SizeOf("abc"))
+	assert.Equal(2, helper.SizeOf([]byte("ab")))
+	assert.Equal(8, helper.SizeOf(int64(1)))
+	assert.Equal(8, helper.SizeOf(3.5))
+}
+
+func TestRunBatch(t *testing.T) {
+	assert := assert.New(t)
+	cluster := newTestCluster(t, nil)
+	spec := &batchSpec{isWrite: true, queue: queuePerKey(func(ctx context.Context, pipe goredis.Pipeliner, key string) goredis.Cmder {
+		return pipe.Get(ctx, key)
+	})}
+
+	// every key fails on its unavailable master, and keeps its own result.
+	result := cluster.runBatch(context.Background(), []string{"a", "b", "a"}, spec)
+	assert.Len(result.Results, 3)
+	assert.True(result.Results[0] == result.Results[2])
+	assert.Equal([]string{"a", "b"}, result.FailedKeys())
+	assert.Equal([]string{}, result.SucceededKeys())
+	assert.NotNil(result.Err())
+	assert.Nil(result.Get("c"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	result = cluster.runBatch(ctx, []string{"a", "b"}, spec)
+	assert.Equal(context.Canceled, result.Get("a").Err)
+	assert.Equal(context.Canceled, result.Get("b").Err)
+}
+
+// a slot of bbbb is migrating to aaaa, and only "{m}2" is moved yet.
+func newMigratingFakeCluster(t *testing.T) (*RedisCluster, *fakeCluster) {
+	cluster, fake := newFakeCluster(t, nil)
+	for key, val := range map[string]string{"{m}1": "1", "{m}2": "2", "b": "3", "d": "4"} {
+		fake.Set(key, &fakeEntry{Type: "string", Str: val})
+	}
+	fake.Migrate(NewCRC16().HashSlot("{m}1"), "aaaa", "{m}2")
+	return cluster, fake
+}
+
+func TestRunBatchAsk(t *testing.T) {
+	assert := assert.New(t)
+	cluster, fake := newMigratingFakeCluster(t)
+
+	// only the key asked is resent to the target, after ASKING.
+	result := cluster.MGetWithResult(context.Background(), "{m}2", "b", "d")
+	assert.Nil(result.Err())
+	assert.Equal("2", result.Get("{m}2").Val)
+	assert.Equal("3", result.Get("b").Val)
+	assert.Equal("4", result.Get("d").Val)
+	assert.Equal([][]string{{"mget", "b"}, {"asking"}, {"mget", "{m}2"}}, fake.NodeCmds("aaaa"))
+	assert.ElementsMatch([][]string{{"mget", "{m}2"}, {"mget", "d"}}, fake.NodeCmds("bbbb"))
+}
+
+func TestRunBatchTryAgain(t *testing.T) {
+	assert := assert.New(t)
+	cluster, fake := newMigratingFakeCluster(t)
+
+	// the keys of the TRYAGAIN are retried one by one even to fail fast, and
+	// the one missing follows its ASK.
+	ctx := WithBatchOptions(context.Background(), &BatchOptions{FailFast: true})
+	result := cluster.MGetWithResult(ctx, "{m}1", "{m}2", "d")
+	assert.Nil(result.Err())
+	assert.Equal("1", result.Get("{m}1").Val)
+	assert.Equal("2", result.Get("{m}2").Val)
+	assert.Equal("4", result.Get("d").Val)
+	assert.Equal([][]string{{"mget", "{m}1", "{m}2"}, {"mget", "d"}, {"mget", "{m}1"}, {"mget", "{m}2"}}, fake.NodeCmds("bbbb"))
+	assert.Equal([][]string{{"asking"}, {"mget", "{m}2"}}, fake.NodeCmds("aaaa"))
+}
+
+func TestRunBatchMoved(t *testing.T) {
+	assert := assert.New(t)
+	cluster, fake := newFakeCluster(t, nil)
+	fake.Set("b", &fakeEntry{Type: "string", Str: "1"})
+	fake.Set("d", &fakeEntry{Type: "string", Str: "2"})
+
+	// the slot table is stale with the ranges of the nodes swapped, and the
+	// refresh waits for the rate limit.
+	addrA, addrB := fake.nodes[0].addr(), fake.nodes[1].addr()
+	assert.Nil(cluster.nodes.ParseAndSet(fmt.Sprintf("aaaa %s@1 master - 0 0 1 connected 8192-16383\n"+
+		"bbbb %s@1 master - 0 0 2 connected 0-8191\n", addrA, addrB)))
+	atomic.StoreInt64(&cluster.lastRefreshAt, time.Now().UnixNano())
+	assert.Equal(int32(0), atomic.LoadInt32(&cluster.isRefreshPending))
+
+	// the moved keys are retried once on their masters.
+	result := cluster.MGetWithResult(context.Background(), "b", "d")
+	assert.Nil(result.Err())
+	assert.Equal("1", result.Get("b").Val)
+	assert.Equal("2", result.Get("d").Val)
+	assert.Equal(addrA, result.Get("b").Addr)
+	assert.Equal(addrB, result.Get("d").Addr)
+	assert.Equal([][]string{{"mget", "d"}, {"mget", "b"}}, fake.NodeCmds("aaaa"))
+	assert.Equal([][]string{{"mget", "b"}, {"mget", "d"}}, fake.NodeCmds("bbbb"))
+
+	// the slots are patched, and a full refresh is scheduled.
+	group, _ := cluster.nodes.FindNodeByCRC16Val(NewCRC16().HashSlot("b"))
+	assert.Equal("aaaa", group.master.Id)
+	group, _ = cluster.nodes.FindNodeByCRC16Val(NewCRC16().HashSlot("d"))
+	assert.Equal("bbbb", group.master.Id)
+	assert.Equal(int32(1), atomic.LoadInt32(&cluster.isRefreshPending))
+}
+
+func TestQueuePerSlot(t *testing.T) {
+	assert := assert.New(t)
+	// the keys of a slot share one call, in their order.
+	queue := queuePerSlot(func(ctx context.Context, pipe goredis.Pipeliner, keys []string) goredis.Cmder {
+		return goredis.NewSliceCmd(ctx, "mget", keys)
+	})
+	calls := queue(context.Background(), nil, []string{"{u1}a", "{u2}a", "{u1}b", "{u2}b", "c"})
+	assert.Len(calls, 3)
+	assert.Equal([]string{"{u1}a", "{u1}b"}, calls[0].Keys)
+	assert.Equal([]string{"{u2}a", "{u2}b"}, calls[1].Keys)
+	assert.Equal([]string{"c"}, calls[2].Keys)
+}
+
+func TestSumBatch(t *testing.T) {
+	assert := assert.New(t)
+	cluster := newTestCluster(t, nil)
+	ctx := context.Background()
+
+	spec := &batchSpec{isWrite: true, queue: queuePerKey(func(ctx context.Context, pipe goredis.Pipeliner, key string) goredis.Cmder {
+		return pipe.Persist(ctx, key)
+	})}
+	cmd := cluster.sumBatch(ctx, "persist", []string{"a", "b"}, spec)
+	assert.Equal([]interface{}{"persist", "a", "b"}, cmd.Args())
+	assert.Equal(int64(0), cmd.Val())
+	assert.NotNil(cmd.Err())
+
+	// the counts and the booleans are both counted.
+	intCmd := goredis.NewIntCmd(ctx, "unlink", "a", "c")
+	intCmd.SetVal(2)
+	boolCmd := goredis.NewBoolCmd(ctx, "persist", "b")
+	boolCmd.SetVal(true)
+	assert.Equal(int64(2), cluster.countOf(intCmd))
+	assert.Equal(int64(1), cluster.countOf(boolCmd))
+	assert.Equal(int64(0), cluster.countOf(goredis.NewStatusCmd(ctx, "ping")))
+}
+
+func TestMPersist(t *testing.T) {
+	assert := assert.New(t)
+	cluster, fake := newFakeCluster(t, nil)
+	ctx := context.Background()
+
+	expireAt := time.Now().Add(time.Minute).UnixNano() / 1e6
+	fake.Set("a", &fakeEntry{Type: "string", Str: "1", ExpireAt: expireAt})
+	fake.Set("b", &fakeEntry{Type: "string", Str: "2", ExpireAt: expireAt})
+	fake.Set("c", &fakeEntry{Type: "string", Str: "3"})
+
+	// only the keys with a ttl are counted.
+	cmd := cluster.MPersist(ctx, "a", "b", "c", "d")
+	assert.Nil(cmd.Err())
+	assert.Equal(int64(2), cmd.Val())
+	assert.Equal(int64(0), fake.Get("a").ExpireAt)
+	assert.Equal(int64(0), fake.Get("b").ExpireAt)
+
+	// the single key Persist of go-redis is kept.
+	fake.Set("a", &fakeEntry{Type: "string", Str: "1", ExpireAt: expireAt})
+	assert.True(cluster.Persist(ctx, "a").Val())
+	assert.False(cluster.Persist(ctx, "a").Val())
+}
+
+func TestUnlinkTouchExists(t *testing.T) {
+	assert := assert.New(t)
+	cluster, fake := newFakeCluster(t, nil)
+	ctx := context.Background()
+
+	for _, key := range []string{"a", "b", "{a}c"} {
+		fake.Set(key, &fakeEntry{Type: "string", Str: key})
+	}
+	keys := []string{"a", "b", "a", "missing", "{a}c"}
+
+	// the counts are summed across the nodes, a duplicated key is counted
+	// as many times as given.
+	cmd := cluster.Exists(ctx, keys...)
+	assert.Nil(cmd.Err())
+	assert.Equal(int64(4), cmd.Val())
+	assert.Contains(fake.NodeCmds("bbbb"), []string{"exists", "a", "a", "{a}c"})
+	assert.Equal(int64(4), cluster.Touch(ctx, keys...).Val())
+	assert.Contains(fake.NodeCmds("bbbb"), []string{"touch", "a", "a", "{a}c"})
+
+	// a duplicated key is removed once.
+	cmd = cluster.Unlink(ctx, keys...)
+	assert.Nil(cmd.Err())
+	assert.Equal(int64(3), cmd.Val())
+	assert.Nil(fake.Get("a"))
+	assert.Nil(fake.Get("b"))
+	assert.Nil(fake.Get("{a}c"))
+	assert.Equal(int64(0), cluster.Exists(ctx, keys...).Val())
+}
+
+func TestGetKeyNodesMapSchedulesRefresh(t *testing.T) {
+	assert := assert.New(t)
+	cluster := newTestCluster(t, nil)
+	assert.Nil(cluster.nodes.ParseAndSet("aaaa 10.0.0.1:6379@16379 master,fail - 0 0 1 connected 0-8191\n"))
+
+	// the refresh waits for the rate limit, and stops on the close.
+	atomic.StoreInt64(&cluster.lastRefreshAt, time.Now().UnixNano())
+	defer close(cluster.closeCh)
+
+	keyNodesMap, missedKeys := cluster.getKeyNodesMap([]string{"a", "b"})
+	assert.Len(keyNodesMap, 1)
+	assert.Equal([]string{"a"}, missedKeys)
+	assert.Equal(int32(1), atomic.LoadInt32(&cluster.isRefreshPending))
+}
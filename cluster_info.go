@@ -29,17 +29,11 @@ type clusterInfo struct {
 	Cluster_stats_messages_meet_received uint64
 }
 
-var (
-	oneClusterInfo *clusterInfo
-)
-
 func NewClusterInfo(info string) *clusterInfo {
-	if oneClusterInfo == nil {
-		oneClusterInfo = &clusterInfo{}
-	}
-	oneClusterInfo.ParseAndSetInfo(info)
+	newInfo := &clusterInfo{}
+	newInfo.ParseAndSetInfo(info)
 
-	return oneClusterInfo
+	return newInfo
 }
 
 func (this *clusterInfo) str2uint64(str string) uint64 {
@@ -8,6 +8,7 @@ package redis
 
 import (
 	"fmt"
+	"sync"
 )
 
 const (
@@ -57,18 +58,15 @@ type CRC16 struct {
 }
 
 var (
-	oneCRC16 *CRC16
-
-	chars = [52]int{}
+	chars     = [52]int{}
+	charsOnce sync.Once
 )
 
 func NewCRC16() *CRC16 {
-	if oneCRC16 == nil {
-		oneCRC16 = &CRC16{}
-		oneCRC16.initChars()
-	}
+	newCRC16 := &CRC16{}
+	charsOnce.Do(newCRC16.initChars)
 
-	return oneCRC16
+	return newCRC16
 }
 
 func (this *CRC16) encode(buf string) uint16 {
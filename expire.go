@@ -0,0 +1,97 @@
+// Copyright (C) 2022
+// Author FrankXu <frankxury@gmail.com>
+// Build on 2026/10/18
+
+// The batch expiry of the keys across the shards.
+
+package redis
+
+import (
+	"context"
+	"fmt"
+	goredis "github.com/go-redis/redis/v8"
+	"log"
+	"sort"
+	"time"
+)
+
+const (
+	EXPIRE_MODE_DEFAULT = ""   // set the ttl anyway.
+	EXPIRE_MODE_NX      = "nx" // only when the key has no ttl.
+	EXPIRE_MODE_XX      = "xx" // only when the key has a ttl.
+	EXPIRE_MODE_GT      = "gt" // only when the new ttl is greater.
+	EXPIRE_MODE_LT      = "lt" // only when the new ttl is less.
+)
+
+// MExpire sets the ttl in seconds of every key, and records per key whether
+// the ttl was set (true), false for a missing key or a mode not met. The
+// modes need Redis 7.
+func (this *RedisCluster) MExpire(ctx context.Context, dur time.Duration, mode string, keys ...string) *BatchResult {
+	return this.runExpire(ctx, keys, mode, func(key string) []interface{} {
+		return []interface{}{"expire", key, formatExpireTTL(dur, time.Second)}
+	})
+}
+
+// MPExpire sets the ttl in milliseconds of every key, like MExpire.
+func (this *RedisCluster) MPExpire(ctx context.Context, dur time.Duration, mode string, keys ...string) *BatchResult {
+	return this.runExpire(ctx, keys, mode, func(key string) []interface{} {
+		return []interface{}{"pexpire", key, formatExpireTTL(dur, time.Millisecond)}
+	})
+}
+
+// MExpireAt sets the unix time in seconds when every key expires, like MExpire.
+func (this *RedisCluster) MExpireAt(ctx context.Context, tm time.Time, mode string, keys ...string) *BatchResult {
+	return this.runExpire(ctx, keys, mode, func(key string) []interface{} {
+		return []interface{}{"expireat", key, tm.Unix()}
+	})
+}
+
+// MExpireMap sets the ttl in milliseconds of every key of the map, like
+// MExpire, the results are in the order of the keys sorted.
+func (this *RedisCluster) MExpireMap(ctx context.Context, ttlMap map[string]time.Duration, mode string) *BatchResult {
+	keys := make([]string, 0, len(ttlMap))
+	for key := range ttlMap {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return this.runExpire(ctx, keys, mode, func(key string) []interface{} {
+		return []interface{}{"pexpire", key, formatExpireTTL(ttlMap[key], time.Millisecond)}
+	})
+}
+
+// to convert the ttl to the unit, a positive ttl less than the unit is
+// truncated to 1 like go-redis, rather than 0 which deletes the key.
+func formatExpireTTL(dur time.Duration, unit time.Duration) int64 {
+	if dur > 0 && dur < unit {
+		log.Printf("specified duration is %s, but minimal supported value is %s - truncating to %s", dur, unit, unit)
+		return 1
+	}
+	return int64(dur / unit)
+}
+
+// to send one expiry command per key, the args are given without the mode.
+func (this *RedisCluster) runExpire(ctx context.Context, keys []string, mode string, argsOf func(key string) []interface{}) *BatchResult {
+	switch mode {
+	case EXPIRE_MODE_DEFAULT, EXPIRE_MODE_NX, EXPIRE_MODE_XX, EXPIRE_MODE_GT, EXPIRE_MODE_LT:
+	default:
+		return newBatchErrResult(fmt.Errorf("unknown expire mode: %s", mode))
+	}
+
+	return this.runBatch(ctx, keys, this.expireSpec(mode, argsOf))
+}
+
+func (this *RedisCluster) expireSpec(mode string, argsOf func(key string) []interface{}) *batchSpec {
+	return &batchSpec{
+		isWrite: true,
+		queue: queuePerKey(func(ctx context.Context, pipe goredis.Pipeliner, key string) goredis.Cmder {
+			args := argsOf(key)
+			if mode != EXPIRE_MODE_DEFAULT {
+				args = append(args, mode)
+			}
+			cmd := goredis.NewBoolCmd(ctx, args...)
+			pipe.Process(ctx, cmd)
+			return cmd
+		}),
+	}
+}
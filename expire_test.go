@@ -0,0 +1,96 @@
+// Copyright (C) 2022
+// Author FrankXu <frankxury@gmail.com>
+// Build on 2026/10/18
+
+// The batch expiry test.
+
+package redis
+
+import (
+	"context"
+	goredis "github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestMExpire(t *testing.T) {
+	assert := assert.New(t)
+	cluster := newTestCluster(t, nil)
+	ctx := context.Background()
+
+	result := cluster.MExpire(ctx, time.Minute, "yy", "a")
+	assert.Equal("unknown expire mode: yy", result.Err().Error())
+
+	result = cluster.MExpireMap(ctx, map[string]time.Duration{"b": time.Second, "a": time.Minute}, EXPIRE_MODE_GT)
+	assert.Equal([]string{"a", "b"}, result.UniqueKeys())
+}
+
+func TestMExpireOnFake(t *testing.T) {
+	assert := assert.New(t)
+	cluster, fake := newFakeCluster(t, nil)
+	ctx := context.Background()
+
+	nowMs := time.Now().UnixNano() / 1e6
+	fake.Set("a", &fakeEntry{Type: "string", Str: "1"})
+	fake.Set("b", &fakeEntry{Type: "string", Str: "2", ExpireAt: nowMs + 60000})
+
+	// every key gets its own ttl, the missing one is false.
+	result := cluster.MExpireMap(ctx, map[string]time.Duration{"a": 10 * time.Second, "b": 20 * time.Second, "c": time.Second}, EXPIRE_MODE_DEFAULT)
+	assert.Nil(result.Err())
+	assert.Equal(true, result.Get("a").Val)
+	assert.Equal(true, result.Get("b").Val)
+	assert.Equal(false, result.Get("c").Val)
+	assert.InDelta(nowMs+10000, fake.Get("a").ExpireAt, 1000)
+	assert.InDelta(nowMs+20000, fake.Get("b").ExpireAt, 1000)
+
+	// only the ttl greater is set by GT.
+	result = cluster.MExpire(ctx, 15*time.Second, EXPIRE_MODE_GT, "a", "b")
+	assert.Nil(result.Err())
+	assert.Equal(true, result.Get("a").Val)
+	assert.Equal(false, result.Get("b").Val)
+	assert.InDelta(nowMs+15000, fake.Get("a").ExpireAt, 1000)
+
+	// only the key without ttl is set by NX.
+	fake.Set("c", &fakeEntry{Type: "string", Str: "3"})
+	result = cluster.MPExpire(ctx, 5*time.Second, EXPIRE_MODE_NX, "a", "c")
+	assert.Equal(false, result.Get("a").Val)
+	assert.Equal(true, result.Get("c").Val)
+
+	// a ttl less than the unit keeps the key instead of deleting it.
+	result = cluster.MExpire(ctx, 500*time.Millisecond, EXPIRE_MODE_DEFAULT, "a")
+	assert.Equal(true, result.Get("a").Val)
+	assert.NotNil(fake.Get("a"))
+	result = cluster.MPExpire(ctx, 500*time.Microsecond, EXPIRE_MODE_DEFAULT, "b")
+	assert.Equal(true, result.Get("b").Val)
+	assert.NotNil(fake.Get("b"))
+}
+
+func TestFormatExpireTTL(t *testing.T) {
+	assert := assert.New(t)
+	assert.Equal(int64(2), formatExpireTTL(2500*time.Millisecond, time.Second))
+	assert.Equal(int64(1), formatExpireTTL(500*time.Millisecond, time.Second))
+	assert.Equal(int64(1), formatExpireTTL(time.Microsecond, time.Millisecond))
+	assert.Equal(int64(0), formatExpireTTL(0, time.Second))
+	assert.Equal(int64(-1), formatExpireTTL(-time.Second, time.Second))
+}
+
+func TestExpireSpec(t *testing.T) {
+	assert := assert.New(t)
+	cluster := newTestCluster(t, nil)
+	ctx := context.Background()
+
+	// the pipeline only queues the commands, it is never executed.
+	pipe := goredis.NewClient(&goredis.Options{}).Pipeline()
+	argsOf := func(key string) []interface{} {
+		return []interface{}{"pexpire", key, int64(1500)}
+	}
+
+	calls := cluster.expireSpec(EXPIRE_MODE_NX, argsOf).queue(ctx, pipe, []string{"a", "b"})
+	assert.Len(calls, 2)
+	assert.Equal([]interface{}{"pexpire", "b", int64(1500), "nx"}, calls[1].Cmd.Args())
+
+	calls = cluster.expireSpec(EXPIRE_MODE_DEFAULT, argsOf).queue(ctx, pipe, []string{"a"})
+	assert.Equal([]interface{}{"pexpire", "a", int64(1500)}, calls[0].Cmd.Args())
+	assert.Equal(3, pipe.Len())
+}
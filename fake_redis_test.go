@@ -0,0 +1,916 @@
+// Copyright (C) 2022
+// Author FrankXu <frankxury@gmail.com>
+// Build on 2026/10/18
+
+// The fake redis cluster for the tests: every node serves a range of slots
+// in memory, speaks RESP, replies MOVED and CROSSSLOT like redis, and runs
+// the scripts by gopher-lua.
+
+package redis
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	lua "github.com/yuin/gopher-lua"
+	"io"
+	"math"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type fakeStatus string
+
+// fakeEntry is a value of the fake, serialized as it is by the fake DUMP.
+type fakeEntry struct {
+	Type     string             `json:"type"`
+	Str      string             `json:"str,omitempty"`
+	Hash     map[string]string  `json:"hash,omitempty"`
+	Set      map[string]bool    `json:"set,omitempty"`
+	ZSet     map[string]float64 `json:"zset,omitempty"`
+	ExpireAt int64              `json:"-"` // unix milliseconds, 0 for no ttl.
+}
+
+type fakeNode struct {
+	id        string
+	startSlot uint16
+	endSlot   uint16
+	cluster   *fakeCluster
+	listener  net.Listener
+	data      map[string]*fakeEntry
+	isAsking  bool // the command in execution follows an ASKING.
+}
+
+type fakeCluster struct {
+	lock  sync.Mutex // one lock for all the nodes, like one thread per node.
+	nodes []*fakeNode
+	cmds  [][]string // the node id with the commands received, in order.
+	hook  func(args []string)
+
+	migrating map[uint16]*fakeNode // the slots in migration to the nodes.
+	curNode   *fakeNode            // the node executing the command.
+}
+
+const (
+	FAKE_WRONGTYPE = "WRONGTYPE Operation against a key holding the wrong kind of value"
+	FAKE_DUMP      = "FAKEDUMP"
+)
+
+// newFakeCluster starts the nodes aaaa (0-8191) and bbbb (8192-16383), and
+// connects a cluster object to them.
+func newFakeCluster(t *testing.T, ext *ExtOptions) (*RedisCluster, *fakeCluster) {
+	fake := &fakeCluster{migrating: map[uint16]*fakeNode{}}
+	for _, one := range []*fakeNode{{id: "aaaa", startSlot: 0, endSlot: 8191}, {id: "bbbb", startSlot: 8192, endSlot: 16383}} {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		if !assert.Nil(t, err) {
+			t.FailNow()
+		}
+		one.cluster, one.listener, one.data = fake, listener, map[string]*fakeEntry{}
+		fake.nodes = append(fake.nodes, one)
+		go one.serve()
+	}
+
+	if ext == nil {
+		ext = &ExtOptions{}
+	}
+	ext.TopologySource = TOPOLOGY_SOURCE_NODES
+	cluster, err := NewClusterClientWithExt(context.Background(), &ClusterOptions{Addrs: []string{fake.nodes[0].addr()}}, ext)
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+
+	t.Cleanup(func() {
+		cluster.Close()
+		for _, one := range fake.nodes {
+			one.listener.Close()
+		}
+	})
+	return cluster, fake
+}
+
+func (this *fakeNode) addr() string {
+	return this.listener.Addr().String()
+}
+
+func (this *fakeCluster) owner(slot uint16) *fakeNode {
+	for _, one := range this.nodes {
+		if slot >= one.startSlot && slot <= one.endSlot {
+			return one
+		}
+	}
+	return nil
+}
+
+// to get the data holding the key: a slot in migration is on both the nodes,
+// the node executing sees its own, and the tests see the key wherever it is.
+func (this *fakeCluster) dataOf(key string) map[string]*fakeEntry {
+	slot := NewCRC16().HashSlot(key)
+	if target, isMigrating := this.migrating[slot]; isMigrating {
+		if this.curNode == target {
+			return target.data
+		} else if _, isExists := target.data[key]; this.curNode == nil && isExists {
+			return target.data
+		}
+	}
+	return this.owner(slot).data
+}
+
+// to get the entry of the key on its node, the expired one is removed.
+func (this *fakeCluster) entry(key string) *fakeEntry {
+	data := this.dataOf(key)
+	one, isExists := data[key]
+	if isExists && one.ExpireAt > 0 && one.ExpireAt <= time.Now().UnixNano()/1e6 {
+		delete(data, key)
+		return nil
+	}
+	return one
+}
+
+func (this *fakeCluster) setEntry(key string, one *fakeEntry) {
+	data := this.dataOf(key)
+	if one == nil {
+		delete(data, key)
+	} else {
+		data[key] = one
+	}
+}
+
+// Get returns the entry of the key, for the assertions.
+func (this *fakeCluster) Get(key string) *fakeEntry {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	return this.entry(key)
+}
+
+// Set stores the entry of the key, for the fixtures.
+func (this *fakeCluster) Set(key string, one *fakeEntry) {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	this.setEntry(key, one)
+}
+
+// SetHook sets the fn called before every command with the lock held, so it
+// changes the data by setEntry.
+func (this *fakeCluster) SetHook(fn func(args []string)) {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	this.hook = fn
+}
+
+// Migrate starts to migrate the slot to the node of the id, the keys given
+// are moved already. The owner replies ASK for the keys missing, and TRYAGAIN
+// for a multi-key command with some keys missing, like redis.
+func (this *fakeCluster) Migrate(slot uint16, id string, movedKeys ...string) {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+
+	for _, one := range this.nodes {
+		if one.id == id {
+			this.migrating[slot] = one
+			for _, key := range movedKeys {
+				if val, isExists := this.owner(slot).data[key]; isExists {
+					one.data[key] = val
+					delete(this.owner(slot).data, key)
+				}
+			}
+		}
+	}
+}
+
+// Cmds returns the names of the commands received, like "mget".
+func (this *fakeCluster) Cmds() []string {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+
+	names := []string{}
+	for _, args := range this.cmds {
+		names = append(names, args[1])
+	}
+	return names
+}
+
+// NodeCmds returns the commands received by the node of the id, with the args.
+func (this *fakeCluster) NodeCmds(id string) [][]string {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+
+	cmds := [][]string{}
+	for _, args := range this.cmds {
+		if args[0] == id {
+			cmds = append(cmds, args[1:])
+		}
+	}
+	return cmds
+}
+
+// to execute the command as the node, with the lock held.
+func (this *fakeNode) lockedExec(args []string, isAsking bool) interface{} {
+	this.cluster.lock.Lock()
+	defer this.cluster.lock.Unlock()
+
+	this.cluster.curNode, this.isAsking = this, isAsking
+	defer func() {
+		this.cluster.curNode, this.isAsking = nil, false
+	}()
+	return this.exec(args)
+}
+
+func (this *fakeNode) serve() {
+	for {
+		conn, err := this.listener.Accept()
+		if err != nil {
+			return
+		}
+		go this.serveConn(conn)
+	}
+}
+
+func (this *fakeNode) serveConn(conn net.Conn) {
+	defer conn.Close()
+	reader, writer := bufio.NewReader(conn), bufio.NewWriter(conn)
+
+	var queued [][]string
+	isMulti, isAsking := false, false
+	for {
+		args, err := readFakeArgs(reader)
+		if err != nil {
+			return
+		}
+		args[0] = strings.ToLower(args[0])
+
+		var reply interface{}
+		switch {
+		case args[0] == "multi":
+			isMulti, queued, reply = true, [][]string{}, fakeStatus("OK")
+		case args[0] == "exec":
+			replies := []interface{}{}
+			for _, one := range queued {
+				replies = append(replies, this.lockedExec(one, false))
+			}
+			isMulti, reply = false, replies
+		case isMulti:
+			this.cluster.lock.Lock()
+			if err := this.checkKeys(args); err != nil {
+				reply = err
+			} else {
+				queued, reply = append(queued, args), fakeStatus("QUEUED")
+			}
+			this.cluster.lock.Unlock()
+		default:
+			// ASKING only covers the next command.
+			reply, isAsking = this.lockedExec(args, isAsking), args[0] == "asking"
+		}
+
+		writeFakeReply(writer, reply)
+		if reader.Buffered() == 0 {
+			if writer.Flush() != nil {
+				return
+			}
+		}
+	}
+}
+
+func readFakeArgs(reader *bufio.Reader) ([]string, error) {
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(line[1:]))
+	if err != nil || line[0] != '*' || n <= 0 {
+		return nil, errors.New("protocol error")
+	}
+
+	args := make([]string, n)
+	for i := range args {
+		if line, err = reader.ReadString('\n'); err != nil {
+			return nil, err
+		}
+		size, _ := strconv.Atoi(strings.TrimSpace(line[1:]))
+		buf := make([]byte, size+2)
+		if _, err := io.ReadFull(reader, buf); err != nil {
+			return nil, err
+		}
+		args[i] = string(buf[:size])
+	}
+	return args, nil
+}
+
+func writeFakeReply(writer *bufio.Writer, reply interface{}) {
+	switch val := reply.(type) {
+	case nil:
+		writer.WriteString("$-1\r\n")
+	case fakeStatus:
+		fmt.Fprintf(writer, "+%s\r\n", val)
+	case error:
+		fmt.Fprintf(writer, "-%s\r\n", val.Error())
+	case int64:
+		fmt.Fprintf(writer, ":%d\r\n", val)
+	case string:
+		fmt.Fprintf(writer, "$%d\r\n%s\r\n", len(val), val)
+	case []interface{}:
+		fmt.Fprintf(writer, "*%d\r\n", len(val))
+		for _, one := range val {
+			writeFakeReply(writer, one)
+		}
+	}
+}
+
+// to get the keys of the commands, nil for a command without key.
+func fakeKeysOf(args []string) []string {
+	switch args[0] {
+	case "mget", "del", "unlink", "exists", "touch":
+		return args[1:]
+	case "mset":
+		keys := []string{}
+		for i := 1; i < len(args); i += 2 {
+			keys = append(keys, args[i])
+		}
+		return keys
+	case "eval":
+		n, _ := strconv.Atoi(args[2])
+		return args[3 : 3+n]
+	case "rename", "renamenx", "copy":
+		return args[1:3]
+	case "object", "memory":
+		return args[2:3]
+	case "ping", "readonly", "asking", "cluster", "command":
+		return nil
+	}
+	return args[1:2]
+}
+
+// to reply the key positions of the commands, so go-redis routes them by
+// the slots of their keys.
+func fakeCommandInfos() []interface{} {
+	infos := []interface{}{}
+	for _, name := range []string{"get", "set", "mget", "mset", "del", "unlink", "exists", "touch", "type",
+		"dump", "restore", "rename", "renamenx", "copy", "ttl", "pttl", "persist", "expire", "pexpire",
+		"expireat", "hset", "hgetall", "hmget", "hdel", "sadd", "scard", "smembers", "sscan", "zadd", "zrange",
+		"eval", "object", "memory", "cluster", "ping"} {
+		firstKey, lastKey, step := int64(1), int64(1), int64(1)
+		switch name {
+		case "mget", "del", "unlink", "exists", "touch":
+			lastKey = -1
+		case "mset":
+			lastKey, step = -1, 2
+		case "rename", "renamenx", "copy":
+			lastKey = 2
+		case "eval", "memory", "cluster", "ping":
+			firstKey, lastKey, step = 0, 0, 0
+		case "object":
+			firstKey, lastKey = 2, 2
+		}
+		infos = append(infos, []interface{}{name, int64(-1), []interface{}{}, firstKey, lastKey, step})
+	}
+	return infos
+}
+
+// to reply MOVED for a slot of another node, and ASK or TRYAGAIN for a slot
+// in migration, like redis.
+func (this *fakeNode) checkKeys(args []string) error {
+	keys := fakeKeysOf(args)
+	if len(keys) == 0 {
+		return nil
+	}
+
+	slot := NewCRC16().HashSlot(keys[0])
+	for _, key := range keys[1:] {
+		if NewCRC16().HashSlot(key) != slot {
+			return errors.New("CROSSSLOT Keys in request don't hash to the same slot")
+		}
+	}
+
+	owner := this.cluster.owner(slot)
+	target, isMigrating := this.cluster.migrating[slot]
+	if owner != this && !(isMigrating && target == this && this.isAsking) {
+		return fmt.Errorf("MOVED %d %s", slot, owner.addr())
+	} else if owner != this || !isMigrating {
+		return nil
+	}
+
+	existing := 0
+	for _, key := range keys {
+		if _, isExists := this.data[key]; isExists {
+			existing++
+		}
+	}
+	switch existing {
+	case len(keys):
+		return nil
+	case 0:
+		return fmt.Errorf("ASK %d %s", slot, target.addr())
+	}
+	return errors.New("TRYAGAIN Multiple keys request during rehashing of slot")
+}
+
+func (this *fakeNode) exec(args []string) interface{} {
+	if args[0] != "cluster" && args[0] != "command" && args[0] != "readonly" && args[0] != "ping" {
+		this.cluster.cmds = append(this.cluster.cmds, append([]string{this.id}, args...))
+	}
+	if err := this.checkKeys(args); err != nil {
+		return err
+	}
+	if this.cluster.hook != nil {
+		this.cluster.hook(args)
+	}
+
+	fake := this.cluster
+	switch args[0] {
+	case "ping":
+		return fakeStatus("PONG")
+	case "readonly", "asking":
+		return fakeStatus("OK")
+	case "command":
+		return fakeCommandInfos()
+	case "cluster":
+		return this.execCluster(args)
+	case "eval":
+		return this.execEval(args)
+	}
+
+	one := fake.entry(fakeKeysOf(args)[0])
+	switch args[0] {
+	case "get":
+		if one == nil {
+			return nil
+		} else if one.Type != "string" {
+			return errors.New(FAKE_WRONGTYPE)
+		}
+		return one.Str
+	case "set":
+		newOne := &fakeEntry{Type: "string", Str: args[2]}
+		for i := 3; i < len(args); i++ {
+			switch strings.ToLower(args[i]) {
+			case "px":
+				ms, _ := strconv.ParseInt(args[i+1], 10, 64)
+				newOne.ExpireAt, i = time.Now().UnixNano()/1e6+ms, i+1
+			case "keepttl":
+				if one != nil {
+					newOne.ExpireAt = one.ExpireAt
+				}
+			}
+		}
+		fake.setEntry(args[1], newOne)
+		return fakeStatus("OK")
+	case "mget":
+		vals := []interface{}{}
+		for _, key := range args[1:] {
+			if one := fake.entry(key); one != nil && one.Type == "string" {
+				vals = append(vals, one.Str)
+			} else {
+				vals = append(vals, nil)
+			}
+		}
+		return vals
+	case "mset":
+		for i := 1; i+1 < len(args); i += 2 {
+			fake.setEntry(args[i], &fakeEntry{Type: "string", Str: args[i+1]})
+		}
+		return fakeStatus("OK")
+	case "del", "unlink", "exists", "touch":
+		var count int64
+		for _, key := range args[1:] {
+			if fake.entry(key) != nil {
+				count++
+				if args[0] == "del" || args[0] == "unlink" {
+					fake.setEntry(key, nil)
+				}
+			}
+		}
+		return count
+	case "type":
+		if one == nil {
+			return fakeStatus("none")
+		}
+		return fakeStatus(one.Type)
+	case "dump":
+		if one == nil {
+			return nil
+		}
+		blob, _ := json.Marshal(one)
+		return FAKE_DUMP + string(blob)
+	case "restore":
+		return this.execRestore(one, args)
+	case "rename", "renamenx", "copy":
+		return this.execRename(one, args)
+	}
+
+	if one != nil && !fakeTypeMatches(args[0], one.Type) {
+		return errors.New(FAKE_WRONGTYPE)
+	}
+	return this.execTyped(one, args)
+}
+
+func fakeTypeMatches(cmd string, typ string) bool {
+	switch cmd[0] {
+	case 'h':
+		return typ == "hash"
+	case 's':
+		return typ == "set"
+	case 'z':
+		return typ == "zset"
+	}
+	return true
+}
+
+// the commands of one key with its type checked.
+func (this *fakeNode) execTyped(one *fakeEntry, args []string) interface{} {
+	fake, key := this.cluster, args[1]
+	nowMs := time.Now().UnixNano() / 1e6
+
+	switch args[0] {
+	case "ttl", "pttl":
+		switch {
+		case one == nil:
+			return int64(-2)
+		case one.ExpireAt == 0:
+			return int64(-1)
+		case args[0] == "ttl":
+			return (one.ExpireAt - nowMs + 500) / 1000
+		}
+		return one.ExpireAt - nowMs
+	case "persist":
+		if one == nil || one.ExpireAt == 0 {
+			return int64(0)
+		}
+		one.ExpireAt = 0
+		return int64(1)
+	case "expire", "pexpire", "expireat":
+		return this.execExpire(one, args)
+	case "object":
+		if one == nil {
+			return nil
+		}
+		if _, err := strconv.ParseInt(one.Str, 10, 64); one.Type == "string" && err == nil {
+			return "int"
+		} else if one.Type == "string" {
+			return "embstr"
+		}
+		return "listpack"
+	case "memory":
+		if one == nil {
+			return nil
+		}
+		blob, _ := json.Marshal(one)
+		return int64(len(blob))
+	case "hset":
+		if one == nil {
+			one = &fakeEntry{Type: "hash", Hash: map[string]string{}}
+			fake.setEntry(key, one)
+		}
+		var count int64
+		for i := 2; i+1 < len(args); i += 2 {
+			if _, isExists := one.Hash[args[i]]; !isExists {
+				count++
+			}
+			one.Hash[args[i]] = args[i+1]
+		}
+		return count
+	case "hgetall":
+		vals := []interface{}{}
+		if one != nil {
+			for _, field := range fakeSortedKeys(one.Hash) {
+				vals = append(vals, field, one.Hash[field])
+			}
+		}
+		return vals
+	case "hmget":
+		vals := []interface{}{}
+		for _, field := range args[2:] {
+			if val, isExists := one.hashField(field); isExists {
+				vals = append(vals, val)
+			} else {
+				vals = append(vals, nil)
+			}
+		}
+		return vals
+	case "hdel":
+		var count int64
+		for _, field := range args[2:] {
+			if _, isExists := one.hashField(field); isExists {
+				delete(one.Hash, field)
+				count++
+			}
+		}
+		if one != nil && len(one.Hash) == 0 {
+			fake.setEntry(key, nil)
+		}
+		return count
+	case "sadd":
+		if one == nil {
+			one = &fakeEntry{Type: "set", Set: map[string]bool{}}
+			fake.setEntry(key, one)
+		}
+		var count int64
+		for _, member := range args[2:] {
+			if !one.Set[member] {
+				one.Set[member], count = true, count+1
+			}
+		}
+		return count
+	case "scard":
+		if one == nil {
+			return int64(0)
+		}
+		return int64(len(one.Set))
+	case "smembers", "sscan":
+		members := []interface{}{}
+		if one != nil {
+			for member := range one.Set {
+				members = append(members, member)
+			}
+		}
+		if args[0] == "sscan" {
+			return []interface{}{"0", members}
+		}
+		return members
+	case "zadd":
+		if one == nil {
+			one = &fakeEntry{Type: "zset", ZSet: map[string]float64{}}
+			fake.setEntry(key, one)
+		}
+		var count int64
+		for i := 2; i+1 < len(args); i += 2 {
+			score, _ := strconv.ParseFloat(args[i], 64)
+			if _, isExists := one.ZSet[args[i+1]]; !isExists {
+				count++
+			}
+			one.ZSet[args[i+1]] = score
+		}
+		return count
+	case "zrange":
+		vals := []interface{}{}
+		if one != nil {
+			members := fakeSortedKeys(one.ZSet)
+			sort.SliceStable(members, func(i, j int) bool {
+				return one.ZSet[members[i]] < one.ZSet[members[j]]
+			})
+			for _, member := range members {
+				vals = append(vals, member, strconv.FormatFloat(one.ZSet[member], 'f', -1, 64))
+			}
+		}
+		return vals
+	}
+
+	return fmt.Errorf("ERR unknown command '%s'", args[0])
+}
+
+func (this *fakeEntry) hashField(field string) (string, bool) {
+	if this == nil {
+		return "", false
+	}
+	val, isExists := this.Hash[field]
+	return val, isExists
+}
+
+// EXPIRE key seconds [NX|XX|GT|LT], a ttl not positive deletes the key.
+func (this *fakeNode) execExpire(one *fakeEntry, args []string) interface{} {
+	if one == nil {
+		return int64(0)
+	}
+
+	nowMs := time.Now().UnixNano() / 1e6
+	n, _ := strconv.ParseInt(args[2], 10, 64)
+	expireAt := map[string]int64{"expire": nowMs + n*1000, "pexpire": nowMs + n, "expireat": n * 1000}[args[0]]
+
+	curExpireAt := int64(math.MaxInt64)
+	if one.ExpireAt > 0 {
+		curExpireAt = one.ExpireAt
+	}
+	if len(args) > 3 {
+		switch strings.ToLower(args[3]) {
+		case "nx":
+			if one.ExpireAt > 0 {
+				return int64(0)
+			}
+		case "xx":
+			if one.ExpireAt == 0 {
+				return int64(0)
+			}
+		case "gt":
+			if expireAt <= curExpireAt {
+				return int64(0)
+			}
+		case "lt":
+			if expireAt >= curExpireAt {
+				return int64(0)
+			}
+		}
+	}
+
+	if expireAt <= nowMs {
+		this.cluster.setEntry(args[1], nil)
+	} else {
+		one.ExpireAt = expireAt
+	}
+	return int64(1)
+}
+
+// RESTORE key ttl payload [REPLACE]
+func (this *fakeNode) execRestore(one *fakeEntry, args []string) interface{} {
+	if one != nil && (len(args) < 5 || strings.ToLower(args[4]) != "replace") {
+		return errors.New("BUSYKEY Target key name already exists.")
+	}
+
+	newOne := &fakeEntry{}
+	if !strings.HasPrefix(args[3], FAKE_DUMP) || json.Unmarshal([]byte(args[3][len(FAKE_DUMP):]), newOne) != nil {
+		return errors.New("ERR DUMP payload version or checksum are wrong")
+	}
+	if ttl, _ := strconv.ParseInt(args[2], 10, 64); ttl > 0 {
+		newOne.ExpireAt = time.Now().UnixNano()/1e6 + ttl
+	}
+
+	this.cluster.setEntry(args[1], newOne)
+	return fakeStatus("OK")
+}
+
+// RENAME key newkey, RENAMENX key newkey, COPY source dest [REPLACE]
+func (this *fakeNode) execRename(one *fakeEntry, args []string) interface{} {
+	fake := this.cluster
+	if one == nil && args[0] == "copy" {
+		return int64(0)
+	} else if one == nil {
+		return errors.New("ERR no such key")
+	}
+
+	isReplace := args[0] == "rename" || (len(args) > 3 && strings.ToLower(args[3]) == "replace")
+	if fake.entry(args[2]) != nil && !isReplace {
+		return int64(0)
+	}
+
+	if args[0] == "copy" {
+		blob, _ := json.Marshal(one)
+		newOne := &fakeEntry{ExpireAt: one.ExpireAt}
+		json.Unmarshal(blob, newOne)
+		fake.setEntry(args[2], newOne)
+		return int64(1)
+	}
+
+	fake.setEntry(args[1], nil)
+	fake.setEntry(args[2], one)
+	if args[0] == "rename" {
+		return fakeStatus("OK")
+	}
+	return int64(1)
+}
+
+func (this *fakeNode) execCluster(args []string) interface{} {
+	switch strings.ToLower(args[1]) {
+	case "info":
+		return "cluster_state:ok\r\ncluster_slots_assigned:16384\r\ncluster_known_nodes:2\r\n"
+	case "nodes":
+		info := ""
+		for i, one := range this.cluster.nodes {
+			info += fmt.Sprintf("%s %s@1 master - 0 0 %d connected %d-%d\n", one.id, one.addr(), i+1, one.startSlot, one.endSlot)
+		}
+		return info
+	case "slots":
+		slots := []interface{}{}
+		for _, one := range this.cluster.nodes {
+			host, port, _ := net.SplitHostPort(one.addr())
+			portNum, _ := strconv.ParseInt(port, 10, 64)
+			slots = append(slots, []interface{}{int64(one.startSlot), int64(one.endSlot), []interface{}{host, portNum, one.id}})
+		}
+		return slots
+	}
+	return errors.New("ERR unknown subcommand")
+}
+
+// EVAL script numkeys key... arg..., redis.call runs on this node.
+func (this *fakeNode) execEval(args []string) interface{} {
+	L := lua.NewState()
+	defer L.Close()
+
+	n, _ := strconv.Atoi(args[2])
+	keys, argv := L.NewTable(), L.NewTable()
+	for _, key := range args[3 : 3+n] {
+		keys.Append(lua.LString(key))
+	}
+	for _, arg := range args[3+n:] {
+		argv.Append(lua.LString(arg))
+	}
+	L.SetGlobal("KEYS", keys)
+	L.SetGlobal("ARGV", argv)
+
+	call := func(isProtected bool) lua.LGFunction {
+		return func(L *lua.LState) int {
+			cmdArgs := []string{}
+			for i := 1; i <= L.GetTop(); i++ {
+				cmdArgs = append(cmdArgs, L.ToString(i))
+			}
+			cmdArgs[0] = strings.ToLower(cmdArgs[0])
+
+			reply := this.exec(cmdArgs)
+			if err, isErr := reply.(error); isErr && !isProtected {
+				L.RaiseError("%s", err.Error())
+			}
+			L.Push(toLuaValue(L, reply))
+			return 1
+		}
+	}
+	redisTable := L.NewTable()
+	L.SetField(redisTable, "call", L.NewFunction(call(false)))
+	L.SetField(redisTable, "pcall", L.NewFunction(call(true)))
+	L.SetGlobal("redis", redisTable)
+
+	fn, err := L.LoadString(args[1])
+	if err == nil {
+		L.Push(fn)
+		err = L.PCall(0, 1, nil)
+	}
+	if err != nil {
+		return fmt.Errorf("ERR Error running script: %s", err.Error())
+	}
+	return fromLuaValue(L.Get(-1))
+}
+
+func toLuaValue(L *lua.LState, reply interface{}) lua.LValue {
+	switch val := reply.(type) {
+	case int64:
+		return lua.LNumber(val)
+	case string:
+		return lua.LString(val)
+	case fakeStatus:
+		table := L.NewTable()
+		L.SetField(table, "ok", lua.LString(val))
+		return table
+	case error:
+		table := L.NewTable()
+		L.SetField(table, "err", lua.LString(val.Error()))
+		return table
+	case []interface{}:
+		table := L.NewTable()
+		for _, one := range val {
+			table.Append(toLuaValue(L, one))
+		}
+		return table
+	}
+	return lua.LFalse
+}
+
+func fromLuaValue(value lua.LValue) interface{} {
+	switch val := value.(type) {
+	case lua.LNumber:
+		return int64(val)
+	case lua.LString:
+		return string(val)
+	case lua.LBool:
+		if val {
+			return int64(1)
+		}
+	case *lua.LTable:
+		if ok := val.RawGetString("ok"); ok != lua.LNil {
+			return fakeStatus(ok.String())
+		}
+		if err := val.RawGetString("err"); err != lua.LNil {
+			return errors.New(err.String())
+		}
+		vals := []interface{}{}
+		for i := 1; val.RawGetInt(i) != lua.LNil; i++ {
+			vals = append(vals, fromLuaValue(val.RawGetInt(i)))
+		}
+		return vals
+	}
+	return nil
+}
+
+func fakeSortedKeys(m interface{}) []string {
+	keys := []string{}
+	switch val := m.(type) {
+	case map[string]string:
+		for key := range val {
+			keys = append(keys, key)
+		}
+	case map[string]float64:
+		for key := range val {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Keys on the two nodes, "a" and "b" hash to different slots of different
+// nodes while "{u}a" and "{u}b" share one slot.
+func TestFakeCluster(t *testing.T) {
+	assert := assert.New(t)
+	cluster, fake := newFakeCluster(t, nil)
+	ctx := context.Background()
+
+	assert.NotEqual(fake.owner(NewCRC16().HashSlot("a")), fake.owner(NewCRC16().HashSlot("b")))
+	assert.Nil(cluster.ClusterClient.Set(ctx, "a", "1", 0).Err())
+	assert.Equal("1", cluster.ClusterClient.Get(ctx, "a").Val())
+	assert.Equal("1", fake.Get("a").Str)
+
+	val, err := cluster.ClusterClient.Eval(ctx, "return redis.call('get', KEYS[1])", []string{"a"}).Result()
+	assert.Nil(err)
+	assert.Equal("1", val)
+}
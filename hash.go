@@ -0,0 +1,139 @@
+// Copyright (C) 2022
+// Author FrankXu <frankxury@gmail.com>
+// Build on 2026/10/18
+
+// The batch hash operations across the shards.
+
+package redis
+
+import (
+	"context"
+	goredis "github.com/go-redis/redis/v8"
+	"sort"
+)
+
+// HGetAllMulti gets all the fields of every hash, a missing key has no field.
+// A key failed is left out of the map, and the first error is returned.
+func (this *RedisCluster) HGetAllMulti(ctx context.Context, keys ...string) (map[string]map[string]string, error) {
+	batchRes := this.runBatch(ctx, keys, &batchSpec{
+		isWrite: false,
+		queue: queuePerKey(func(ctx context.Context, pipe goredis.Pipeliner, key string) goredis.Cmder {
+			return pipe.HGetAll(ctx, key)
+		}),
+	})
+
+	return this.toHashMap(batchRes), batchRes.Err()
+}
+
+// HMGetMulti gets the fields of every hash, the missing fields are left out.
+// A key failed is left out of the map, and the first error is returned.
+func (this *RedisCluster) HMGetMulti(ctx context.Context, fields []string, keys ...string) (map[string]map[string]string, error) {
+	batchRes := this.runBatch(ctx, keys, &batchSpec{
+		isWrite: false,
+		queue: queuePerKey(func(ctx context.Context, pipe goredis.Pipeliner, key string) goredis.Cmder {
+			return pipe.HMGet(ctx, key, fields...)
+		}),
+		parse: func(call *batchCall) []interface{} {
+			fieldMap := map[string]string{}
+			for i, one := range call.Cmd.(*goredis.SliceCmd).Val() {
+				if val, isOk := one.(string); isOk && i < len(fields) {
+					fieldMap[fields[i]] = val
+				}
+			}
+			return []interface{}{fieldMap}
+		},
+	})
+
+	return this.toHashMap(batchRes), batchRes.Err()
+}
+
+// HScanMulti gets all the fields of the hashes, and scans every one into the
+// struct of its key by the `redis` tags. The struct of a missing key is kept.
+func (this *RedisCluster) HScanMulti(ctx context.Context, dests map[string]interface{}) error {
+	keys := make([]string, 0, len(dests))
+	for key := range dests {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	hashMap, err := this.HGetAllMulti(ctx, keys...)
+	for key, fieldMap := range hashMap {
+		if len(fieldMap) == 0 {
+			continue
+		}
+		if scanErr := NewRedisHelper().ScanHash(fieldMap, dests[key]); scanErr != nil && err == nil {
+			err = scanErr
+		}
+	}
+
+	return err
+}
+
+// HSetMulti sets the fields of every hash, and records per key the count of
+// the fields added. The results are in the order of the keys sorted, a key
+// without any field is skipped as HSET needs one at least.
+func (this *RedisCluster) HSetMulti(ctx context.Context, values map[string]map[string]interface{}) *BatchResult {
+	keys := make([]string, 0, len(values))
+	for key, fieldMap := range values {
+		if len(fieldMap) > 0 {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	return this.runBatch(ctx, keys, &batchSpec{
+		isWrite: true,
+		queue: queuePerKey(func(ctx context.Context, pipe goredis.Pipeliner, key string) goredis.Cmder {
+			fields := make([]string, 0, len(values[key]))
+			for field := range values[key] {
+				fields = append(fields, field)
+			}
+			sort.Strings(fields)
+
+			pairs := make([]interface{}, 0, len(fields)*2)
+			for _, field := range fields {
+				pairs = append(pairs, field, values[key][field])
+			}
+			return pipe.HSet(ctx, key, pairs...)
+		}),
+		sizeOf: func(key string) int {
+			size := len(key)
+			for field, val := range values[key] {
+				size += len(field) + NewRedisHelper().SizeOf(val)
+			}
+			return size
+		},
+	})
+}
+
+// HDelMulti deletes the fields from every hash, and counts the fields deleted.
+// No field deletes nothing, so 0 is replied without any command sent.
+func (this *RedisCluster) HDelMulti(ctx context.Context, fields []string, keys ...string) *goredis.IntCmd {
+	if len(fields) == 0 {
+		keyInfs := append([]interface{}{"hdel"}, this.strArr2InfArr(keys)...)
+		return goredis.NewIntCmd(ctx, keyInfs...)
+	}
+
+	return this.sumBatch(ctx, "hdel", keys, &batchSpec{
+		isWrite: true,
+		queue: queuePerKey(func(ctx context.Context, pipe goredis.Pipeliner, key string) goredis.Cmder {
+			return pipe.HDel(ctx, key, fields...)
+		}),
+	})
+}
+
+// to map the fields by the keys succeeded.
+func (this *RedisCluster) toHashMap(batchRes *BatchResult) map[string]map[string]string {
+	hashMap := make(map[string]map[string]string, len(batchRes.Results))
+	for _, one := range batchRes.Results {
+		if one.Err != nil {
+			continue
+		}
+		fieldMap, _ := one.Val.(map[string]string)
+		if fieldMap == nil {
+			fieldMap = map[string]string{}
+		}
+		hashMap[one.Key] = fieldMap
+	}
+	return hashMap
+}
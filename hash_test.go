@@ -0,0 +1,99 @@
+// Copyright (C) 2022
+// Author FrankXu <frankxury@gmail.com>
+// Build on 2026/10/18
+
+// The batch hash operations test.
+
+package redis
+
+import (
+	"context"
+	goredis "github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+type testProfile struct {
+	Name string `redis:"name"`
+	Age  int    `redis:"age"`
+}
+
+func TestScanHash(t *testing.T) {
+	assert := assert.New(t)
+	helper := NewRedisHelper()
+
+	profile := &testProfile{}
+	assert.Nil(helper.ScanHash(map[string]string{"name": "frank", "age": "18", "city": "sz"}, profile))
+	assert.Equal(&testProfile{Name: "frank", Age: 18}, profile)
+
+	assert.NotNil(helper.ScanHash(map[string]string{"age": "eighteen"}, profile))
+}
+
+func TestToHashMap(t *testing.T) {
+	assert := assert.New(t)
+	cluster := newTestCluster(t, nil)
+	ctx := context.Background()
+
+	spec := &batchSpec{}
+	result := newBatchResult([]string{"u1", "u2", "u3"})
+
+	allCmd := goredis.NewStringStringMapCmd(ctx, "hgetall", "u1")
+	allCmd.SetVal(map[string]string{"name": "frank"})
+	cluster.setCallResult(result, &batchCall{Keys: []string{"u1"}, Cmd: allCmd}, spec)
+	missCmd := goredis.NewStringStringMapCmd(ctx, "hgetall", "u2")
+	missCmd.SetVal(map[string]string{})
+	cluster.setCallResult(result, &batchCall{Keys: []string{"u2"}, Cmd: missCmd}, spec)
+	result.setKeysErr([]string{"u3"}, ErrSlotNotServed)
+
+	hashMap := cluster.toHashMap(result)
+	assert.Equal(map[string]map[string]string{"u1": {"name": "frank"}, "u2": {}}, hashMap)
+}
+
+func TestHashMulti(t *testing.T) {
+	assert := assert.New(t)
+	cluster, fake := newFakeCluster(t, nil)
+	ctx := context.Background()
+
+	fake.Set("u1", &fakeEntry{Type: "hash", Hash: map[string]string{"name": "tony"}})
+
+	// the key without any field is skipped.
+	result := cluster.HSetMulti(ctx, map[string]map[string]interface{}{
+		"u1": {"name": "frank", "age": 18},
+		"u2": {"name": "tony"},
+		"u3": {},
+	})
+	assert.Nil(result.Err())
+	assert.Equal([]string{"u1", "u2"}, result.UniqueKeys())
+	assert.Equal(int64(1), result.Get("u1").Val)
+	assert.Equal(int64(1), result.Get("u2").Val)
+	assert.Equal(map[string]string{"name": "frank", "age": "18"}, fake.Get("u1").Hash)
+	assert.Nil(fake.Get("u3"))
+
+	hashMap, err := cluster.HGetAllMulti(ctx, "u1", "u2", "u3")
+	assert.Nil(err)
+	assert.Equal(map[string]map[string]string{"u1": {"name": "frank", "age": "18"}, "u2": {"name": "tony"}, "u3": {}}, hashMap)
+
+	hashMap, err = cluster.HMGetMulti(ctx, []string{"age", "city"}, "u1", "u2")
+	assert.Nil(err)
+	assert.Equal(map[string]map[string]string{"u1": {"age": "18"}, "u2": {}}, hashMap)
+
+	// the struct of the missing key is kept.
+	dests := map[string]interface{}{"u1": &testProfile{}, "u3": &testProfile{Name: "kept"}}
+	assert.Nil(cluster.HScanMulti(ctx, dests))
+	assert.Equal(&testProfile{Name: "frank", Age: 18}, dests["u1"])
+	assert.Equal(&testProfile{Name: "kept"}, dests["u3"])
+
+	cmd := cluster.HDelMulti(ctx, []string{"name", "age"}, "u1", "u2", "u3")
+	assert.Nil(cmd.Err())
+	assert.Equal(int64(3), cmd.Val())
+	assert.Nil(fake.Get("u1"))
+
+	// no field sends nothing.
+	fake.Set("u2", &fakeEntry{Type: "hash", Hash: map[string]string{"name": "tony"}})
+	cmdCount := len(fake.Cmds())
+	cmd = cluster.HDelMulti(ctx, []string{}, "u2")
+	assert.Nil(cmd.Err())
+	assert.Equal(int64(0), cmd.Val())
+	assert.Equal([]interface{}{"hdel", "u2"}, cmd.Args())
+	assert.Equal(cmdCount, len(fake.Cmds()))
+}
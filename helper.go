@@ -7,20 +7,32 @@
 package redis
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	goredis "github.com/go-redis/redis/v8"
+	"net"
 	"regexp"
+	"strconv"
 	"strings"
 )
 
+const (
+	REDIRECT_MOVED = "MOVED"
+	REDIRECT_ASK   = "ASK"
+)
+
 type RedisHelper struct{}
 
-var oneRedisHelper *RedisHelper
+// RedirectInfo is the parsed reply of a MOVED or ASK error.
+type RedirectInfo struct {
+	Kind string // support: MOVED or ASK.
+	Slot uint16
+	Addr string
+}
 
 func NewRedisHelper() *RedisHelper {
-	if oneRedisHelper == nil {
-		oneRedisHelper = &RedisHelper{}
-	}
-	return oneRedisHelper
+	return &RedisHelper{}
 }
 
 func (this *RedisHelper) GetKeysInPairInfs(vals []interface{}) ([]string, map[string]interface{}, error) {
@@ -77,3 +89,63 @@ func (this *RedisHelper) IsMovedError(err error) bool {
 	errInfo := err.Error()
 	return strings.Index(errInfo, "MOVED") >= 0 || strings.Index(errInfo, "CROSSSLOT Keys") >= 0
 }
+
+// for examples: TRYAGAIN Multiple keys request during rehashing of slot
+func (this *RedisHelper) IsTryAgainError(err error) bool {
+	return err != nil && strings.HasPrefix(err.Error(), "TRYAGAIN")
+}
+
+func (this *RedisHelper) IsAskError(err error) bool {
+	redirect, isOk := this.ParseRedirectError(err)
+	return isOk && redirect.Kind == REDIRECT_ASK
+}
+
+// for examples:
+// MOVED 3999 127.0.0.1:6381
+// ASK 3999 127.0.0.1:6381
+func (this *RedisHelper) ParseRedirectError(err error) (*RedirectInfo, bool) {
+	if err == nil {
+		return nil, false
+	}
+
+	comps := strings.Fields(err.Error())
+	if len(comps) != 3 || (comps[0] != REDIRECT_MOVED && comps[0] != REDIRECT_ASK) {
+		return nil, false
+	}
+
+	slot, parseErr := strconv.ParseUint(comps[1], 10, 16)
+	if parseErr != nil || slot >= kClusterSlots {
+		return nil, false
+	}
+
+	// to normalize the IPv6 address like the node addresses.
+	addr := comps[2]
+	if host, port := (&redisNode{}).getAddr(addr); host != "" {
+		addr = net.JoinHostPort(host, port)
+	}
+
+	return &RedirectInfo{Kind: comps[0], Slot: uint16(slot), Addr: addr}, true
+}
+
+// SizeOf estimates the payload bytes of a value sent to redis.
+func (this *RedisHelper) SizeOf(val interface{}) int {
+	switch v := val.(type) {
+	case nil:
+		return 0
+	case string:
+		return len(v)
+	case []byte:
+		return len(v)
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64, bool:
+		return 8
+	}
+	return len(fmt.Sprint(val))
+}
+
+// ScanHash scans the fields of a hash into the struct pointed by dest, by
+// the `redis` tags of its fields, like HGETALL scanned by go-redis.
+func (this *RedisHelper) ScanHash(fieldMap map[string]string, dest interface{}) error {
+	cmd := goredis.NewStringStringMapCmd(context.Background(), "hgetall")
+	cmd.SetVal(fieldMap)
+	return cmd.Scan(dest)
+}
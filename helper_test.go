@@ -0,0 +1,55 @@
+// Copyright (C) 2022
+// Author FrankXu <frankxury@gmail.com>
+// Build on 2026/10/18
+
+// The redis helper test.
+
+package redis
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestParseRedirectError(t *testing.T) {
+	testCases := []struct {
+		Err      error
+		IsOk     bool
+		Redirect RedirectInfo
+	}{
+		{errors.New("MOVED 3999 127.0.0.1:6381"), true, RedirectInfo{REDIRECT_MOVED, 3999, "127.0.0.1:6381"}},
+		{errors.New("ASK 16383 172.29.16.7:6379"), true, RedirectInfo{REDIRECT_ASK, 16383, "172.29.16.7:6379"}},
+		{errors.New("ASK 16384 172.29.16.7:6379"), false, RedirectInfo{}},
+		{errors.New("CROSSSLOT Keys in request don't hash to the same slot"), false, RedirectInfo{}},
+		{nil, false, RedirectInfo{}},
+	}
+
+	assert := assert.New(t)
+	helper := NewRedisHelper()
+	for _, test := range testCases {
+		redirect, isOk := helper.ParseRedirectError(test.Err)
+		assert.Equal(test.IsOk, isOk, "%v failed.", test.Err)
+		if isOk {
+			assert.Equal(test.Redirect, *redirect, "%v failed.", test.Err)
+		}
+	}
+
+	assert.True(helper.IsAskError(errors.New("ASK 1 127.0.0.1:6381")))
+	assert.False(helper.IsAskError(errors.New("MOVED 1 127.0.0.1:6381")))
+	assert.True(helper.IsTryAgainError(errors.New("TRYAGAIN Multiple keys request during rehashing of slot")))
+	assert.False(helper.IsTryAgainError(nil))
+}
+
+func TestParseIPv6RedirectError(t *testing.T) {
+	assert := assert.New(t)
+	helper := NewRedisHelper()
+
+	redirect, isOk := helper.ParseRedirectError(errors.New("MOVED 3999 ::1:6381"))
+	assert.True(isOk)
+	assert.Equal("[::1]:6381", redirect.Addr)
+
+	redirect, isOk = helper.ParseRedirectError(errors.New("ASK 3999 [2001:db8::7]:6381"))
+	assert.True(isOk)
+	assert.Equal("[2001:db8::7]:6381", redirect.Addr)
+}
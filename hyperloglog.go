@@ -0,0 +1,265 @@
+// Copyright (C) 2022
+// Author FrankXu <frankxury@gmail.com>
+// Build on 2026/10/18
+
+// The HyperLogLog across the shards, merged by the redis representation.
+
+package redis
+
+import (
+	"context"
+	"errors"
+	goredis "github.com/go-redis/redis/v8"
+	"math"
+)
+
+const (
+	HLL_P         = 14
+	HLL_Q         = 64 - HLL_P
+	HLL_REGISTERS = 1 << HLL_P
+	HLL_BITS      = 6
+	HLL_REG_MAX   = 1<<HLL_BITS - 1
+	HLL_HDR_SIZE  = 16
+	HLL_DENSE_LEN = HLL_HDR_SIZE + (HLL_REGISTERS*HLL_BITS+7)/8
+	HLL_DENSE     = 0
+	HLL_SPARSE    = 1
+	HLL_ALPHA_INF = 0.721347520444481703680
+)
+
+var (
+	ErrInvalidHLL = errors.New("WRONGTYPE Key is not a valid HyperLogLog string value.")
+	ErrWrongType  = errors.New("WRONGTYPE Operation against a key holding the wrong kind of value")
+)
+
+type hllRegisters [HLL_REGISTERS]uint8
+
+// PFCount estimates the cardinality of the union of the HyperLogLogs, the
+// blobs are read from their shards and merged by the client.
+func (this *RedisCluster) PFCount(ctx context.Context, keys ...string) *goredis.IntCmd {
+	if this.isSameSlot(keys...) {
+		return this.ClusterClient.PFCount(ctx, keys...)
+	}
+
+	keyInfs := append([]interface{}{"pfcount"}, this.strArr2InfArr(keys)...)
+	result := goredis.NewIntCmd(ctx, keyInfs...)
+
+	registers, err := this.mergeHLLKeys(ctx, keys)
+	if err != nil {
+		result.SetErr(err)
+		return result
+	}
+
+	result.SetVal(registers.count())
+	return result
+}
+
+// PFMerge merges the HyperLogLogs, and the dest itself, into the dest. The
+// merged blob is written to the shard of the dest in the dense encoding, and
+// the ttl of the dest is kept.
+func (this *RedisCluster) PFMerge(ctx context.Context, dest string, keys ...string) *goredis.StatusCmd {
+	if this.isSameSlot(append([]string{dest}, keys...)...) {
+		return this.ClusterClient.PFMerge(ctx, dest, keys...)
+	}
+
+	keyInfs := append([]interface{}{"pfmerge", dest}, this.strArr2InfArr(keys)...)
+	result := goredis.NewStatusCmd(ctx, keyInfs...)
+
+	registers, err := this.mergeHLLKeys(ctx, append([]string{dest}, keys...))
+	if err == nil {
+		err = this.checkStringKey(ctx, dest)
+	}
+	if err == nil {
+		err = this.ClusterClient.Set(ctx, dest, registers.dense(), goredis.KeepTTL).Err()
+	}
+	if err != nil {
+		result.SetErr(err)
+		return result
+	}
+
+	result.SetVal("OK")
+	return result
+}
+
+// to read the blobs by one GET per key and merge them, a missing key is
+// empty and a key of another type fails by the WRONGTYPE error of redis.
+func (this *RedisCluster) mergeHLLKeys(ctx context.Context, keys []string) (*hllRegisters, error) {
+	batchRes := this.runBatch(ctx, keys, &batchSpec{
+		isWrite: false,
+		queue: queuePerKey(func(ctx context.Context, pipe goredis.Pipeliner, key string) goredis.Cmder {
+			return pipe.Get(ctx, key)
+		}),
+	})
+	if err := batchRes.Err(); err != nil {
+		return nil, err
+	}
+
+	registers := &hllRegisters{}
+	for _, one := range batchRes.Results {
+		if blob, isOk := one.Val.(string); isOk {
+			if err := registers.merge([]byte(blob)); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return registers, nil
+}
+
+// to check the key on its master is a string or missing, as SET overwrites
+// any type.
+func (this *RedisCluster) checkStringKey(ctx context.Context, key string) error {
+	keyType, err := this.ClusterClient.Type(ctx, key).Result()
+	if err != nil {
+		return err
+	} else if keyType != "none" && keyType != "string" {
+		return ErrWrongType
+	}
+	return nil
+}
+
+// merge keeps the max of every register with the blob, dense or sparse.
+func (this *hllRegisters) merge(blob []byte) error {
+	if len(blob) < HLL_HDR_SIZE || string(blob[:4]) != "HYLL" {
+		return ErrInvalidHLL
+	}
+
+	switch blob[4] {
+	case HLL_DENSE:
+		if len(blob) != HLL_DENSE_LEN {
+			return ErrInvalidHLL
+		}
+		for i := 0; i < HLL_REGISTERS; i++ {
+			if val := denseRegister(blob[HLL_HDR_SIZE:], i); val > this[i] {
+				this[i] = val
+			}
+		}
+	case HLL_SPARSE:
+		return this.mergeSparse(blob[HLL_HDR_SIZE:])
+	default:
+		return ErrInvalidHLL
+	}
+
+	return nil
+}
+
+// the opcodes of the sparse encoding:
+// ZERO 00xxxxxx: xxxxxx+1 registers are 0.
+// XZERO 01xxxxxx yyyyyyyy: xxxxxxyyyyyyyy+1 registers are 0.
+// VAL 1vvvvvxx: xx+1 registers are vvvvv+1.
+func (this *hllRegisters) mergeSparse(data []byte) error {
+	idx := 0
+	for i := 0; i < len(data); i++ {
+		opcode := data[i]
+		switch {
+		case opcode&0xc0 == 0x00:
+			idx += int(opcode&0x3f) + 1
+		case opcode&0xc0 == 0x40:
+			if i+1 >= len(data) {
+				return ErrInvalidHLL
+			}
+			idx += (int(opcode&0x3f)<<8 | int(data[i+1])) + 1
+			i++
+		default:
+			val, runLen := (opcode>>2)&0x1f+1, int(opcode&0x03)+1
+			if idx+runLen > HLL_REGISTERS {
+				return ErrInvalidHLL
+			}
+			for j := idx; j < idx+runLen; j++ {
+				if val > this[j] {
+					this[j] = val
+				}
+			}
+			idx += runLen
+		}
+	}
+
+	if idx != HLL_REGISTERS {
+		return ErrInvalidHLL
+	}
+	return nil
+}
+
+// count estimates the cardinality like redis, by the estimator of Otmar Ertl.
+func (this *hllRegisters) count() int64 {
+	// a corrupted register may be up to HLL_REG_MAX, above HLL_Q+1.
+	reghisto := [HLL_REG_MAX + 1]int{}
+	for _, val := range this {
+		reghisto[val]++
+	}
+
+	m := float64(HLL_REGISTERS)
+	z := m * hllTau((m-float64(reghisto[HLL_Q+1]))/m)
+	for j := HLL_Q; j >= 1; j-- {
+		z += float64(reghisto[j])
+		z *= 0.5
+	}
+	z += m * hllSigma(float64(reghisto[0])/m)
+
+	return int64(math.Round(HLL_ALPHA_INF * m * m / z))
+}
+
+// dense returns the blob in the dense encoding, its cached cardinality is
+// marked as invalid so redis counts it again.
+func (this *hllRegisters) dense() []byte {
+	blob := make([]byte, HLL_DENSE_LEN)
+	copy(blob, "HYLL")
+	blob[4] = HLL_DENSE
+	blob[HLL_HDR_SIZE-1] |= 1 << 7
+
+	for i, val := range this {
+		setDenseRegister(blob[HLL_HDR_SIZE:], i, val)
+	}
+	return blob
+}
+
+// the registers of 6 bits are packed from the least significant bit.
+func denseRegister(registers []byte, idx int) uint8 {
+	byteIdx, fb := idx*HLL_BITS/8, uint(idx*HLL_BITS&7)
+	val := registers[byteIdx] >> fb
+	if byteIdx+1 < len(registers) {
+		val |= registers[byteIdx+1] << (8 - fb)
+	}
+	return val & HLL_REG_MAX
+}
+
+func setDenseRegister(registers []byte, idx int, val uint8) {
+	byteIdx, fb := idx*HLL_BITS/8, uint(idx*HLL_BITS&7)
+	registers[byteIdx] &^= HLL_REG_MAX << fb
+	registers[byteIdx] |= val << fb
+	if byteIdx+1 < len(registers) {
+		registers[byteIdx+1] &^= HLL_REG_MAX >> (8 - fb)
+		registers[byteIdx+1] |= val >> (8 - fb)
+	}
+}
+
+func hllSigma(x float64) float64 {
+	if x == 1 {
+		return math.Inf(1)
+	}
+	y, z := 1.0, x
+	for {
+		x *= x
+		zPrime := z
+		z += x * y
+		y += y
+		if zPrime == z {
+			return z
+		}
+	}
+}
+
+func hllTau(x float64) float64 {
+	if x == 0 || x == 1 {
+		return 0
+	}
+	y, z := 1.0, 1-x
+	for {
+		x = math.Sqrt(x)
+		zPrime := z
+		y *= 0.5
+		z -= math.Pow(1-x, 2) * y
+		if zPrime == z {
+			return z / 3
+		}
+	}
+}
@@ -0,0 +1,123 @@
+// Copyright (C) 2022
+// Author FrankXu <frankxury@gmail.com>
+// Build on 2026/10/18
+
+// The HyperLogLog test.
+
+package redis
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+// to build a sparse blob with the register idx set to val, the others are 0.
+func newTestSparseHLL(idx int, val uint8) []byte {
+	blob := append([]byte("HYLL"), HLL_SPARSE, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0)
+	xzero := func(n int) {
+		if n > 0 {
+			blob = append(blob, 0x40|byte((n-1)>>8), byte(n-1))
+		}
+	}
+	xzero(idx)
+	blob = append(blob, 0x80|(val-1)<<2)
+	xzero(HLL_REGISTERS - idx - 1)
+	return blob
+}
+
+func TestHLLMerge(t *testing.T) {
+	assert := assert.New(t)
+
+	registers := &hllRegisters{}
+	assert.Nil(registers.merge(newTestSparseHLL(100, 3)))
+	assert.Nil(registers.merge(newTestSparseHLL(100, 2)))
+	assert.Nil(registers.merge(newTestSparseHLL(HLL_REGISTERS-1, 32)))
+	assert.Equal(uint8(3), registers[100])
+	assert.Equal(uint8(32), registers[HLL_REGISTERS-1])
+
+	// the dense blob keeps every register.
+	registers[7] = HLL_REG_MAX
+	blob := registers.dense()
+	assert.Len(blob, HLL_DENSE_LEN)
+	other := &hllRegisters{}
+	assert.Nil(other.merge(blob))
+	assert.Equal(registers, other)
+
+	assert.Equal(ErrInvalidHLL, other.merge([]byte("not a hll")))
+	assert.Equal(ErrInvalidHLL, other.merge(blob[:HLL_DENSE_LEN-1]))
+	assert.Equal(ErrInvalidHLL, other.merge(newTestSparseHLL(100, 3)[:HLL_HDR_SIZE+2]))
+}
+
+func TestHLLCount(t *testing.T) {
+	assert := assert.New(t)
+
+	registers := &hllRegisters{}
+	assert.Equal(int64(0), registers.count())
+
+	registers[5] = 1
+	assert.Equal(int64(1), registers.count())
+
+	for i := 1; i <= 100; i++ {
+		registers[i*10] = 2
+	}
+	assert.Equal(int64(101), registers.count())
+}
+
+func TestHLLCountCorrupted(t *testing.T) {
+	assert := assert.New(t)
+
+	// the registers above HLL_Q+1 of a corrupted blob do not panic.
+	registers := &hllRegisters{}
+	registers[0] = HLL_REG_MAX
+	assert.NotPanics(func() {
+		registers.count()
+	})
+}
+
+func TestPFOnFake(t *testing.T) {
+	assert := assert.New(t)
+	cluster, fake := newFakeCluster(t, nil)
+	ctx := context.Background()
+
+	registers := &hllRegisters{}
+	registers[100] = 2
+	fake.Set("a", &fakeEntry{Type: "string", Str: string(registers.dense())})
+	fake.Set("b", &fakeEntry{Type: "string", Str: string(newTestSparseHLL(200, 3))})
+
+	cmd := cluster.PFCount(ctx, "a", "b", "missing")
+	assert.Nil(cmd.Err())
+	assert.Equal(int64(2), cmd.Val())
+
+	// the dest is merged with the keys, and keeps its ttl.
+	expireAt := time.Now().Add(time.Minute).UnixNano() / 1e6
+	fake.Set("c", &fakeEntry{Type: "string", Str: string(newTestSparseHLL(300, 1)), ExpireAt: expireAt})
+	assert.Nil(cluster.PFMerge(ctx, "c", "a", "b").Err())
+	merged := &hllRegisters{}
+	assert.Nil(merged.merge([]byte(fake.Get("c").Str)))
+	assert.Equal(uint8(2), merged[100])
+	assert.Equal(uint8(3), merged[200])
+	assert.Equal(uint8(1), merged[300])
+	assert.Equal(expireAt, fake.Get("c").ExpireAt)
+
+	// a key of another type replies WRONGTYPE, the dest is not written.
+	fake.Set("d", &fakeEntry{Type: "set", Set: map[string]bool{"1": true}})
+	assert.Equal(FAKE_WRONGTYPE, cluster.PFCount(ctx, "a", "d").Err().Error())
+	assert.Equal(FAKE_WRONGTYPE, cluster.PFMerge(ctx, "d", "a", "b").Err().Error())
+	assert.Equal("set", fake.Get("d").Type)
+	fake.Set("e", &fakeEntry{Type: "string", Str: "not a hll"})
+	assert.Equal(ErrInvalidHLL, cluster.PFCount(ctx, "a", "e").Err())
+}
+
+func TestCheckStringKey(t *testing.T) {
+	assert := assert.New(t)
+	cluster, fake := newFakeCluster(t, nil)
+	ctx := context.Background()
+
+	fake.Set("a", &fakeEntry{Type: "string", Str: "1"})
+	fake.Set("b", &fakeEntry{Type: "hash", Hash: map[string]string{"f": "1"}})
+	assert.Nil(cluster.checkStringKey(ctx, "a"))
+	assert.Nil(cluster.checkStringKey(ctx, "missing"))
+	assert.Equal(ErrWrongType, cluster.checkStringKey(ctx, "b"))
+}
@@ -0,0 +1,88 @@
+// Copyright (C) 2022
+// Author FrankXu <frankxury@gmail.com>
+// Build on 2026/10/18
+
+// The batch inspection of the keys across the shards.
+
+package redis
+
+import (
+	"context"
+	goredis "github.com/go-redis/redis/v8"
+)
+
+// MTTL gets the ttl of every key, a time.Duration of seconds, -1 for a key
+// without ttl and nil for a missing key.
+func (this *RedisCluster) MTTL(ctx context.Context, keys ...string) (map[string]interface{}, error) {
+	return this.inspectBatch(ctx, keys, func(ctx context.Context, pipe goredis.Pipeliner, key string) goredis.Cmder {
+		return pipe.TTL(ctx, key)
+	})
+}
+
+// MPTTL gets the ttl of every key like MTTL, a time.Duration of milliseconds.
+func (this *RedisCluster) MPTTL(ctx context.Context, keys ...string) (map[string]interface{}, error) {
+	return this.inspectBatch(ctx, keys, func(ctx context.Context, pipe goredis.Pipeliner, key string) goredis.Cmder {
+		return pipe.PTTL(ctx, key)
+	})
+}
+
+// MType gets the type of every key, like "string" or "hash", nil for a missing key.
+func (this *RedisCluster) MType(ctx context.Context, keys ...string) (map[string]interface{}, error) {
+	return this.inspectBatch(ctx, keys, func(ctx context.Context, pipe goredis.Pipeliner, key string) goredis.Cmder {
+		return pipe.Type(ctx, key)
+	})
+}
+
+// MObjectEncoding gets the encoding of every key, like "listpack", nil for a missing key.
+func (this *RedisCluster) MObjectEncoding(ctx context.Context, keys ...string) (map[string]interface{}, error) {
+	return this.inspectBatch(ctx, keys, func(ctx context.Context, pipe goredis.Pipeliner, key string) goredis.Cmder {
+		return pipe.ObjectEncoding(ctx, key)
+	})
+}
+
+// MMemoryUsage gets the bytes used by every key, an int64, nil for a missing key.
+func (this *RedisCluster) MMemoryUsage(ctx context.Context, keys ...string) (map[string]interface{}, error) {
+	return this.inspectBatch(ctx, keys, func(ctx context.Context, pipe goredis.Pipeliner, key string) goredis.Cmder {
+		return pipe.MemoryUsage(ctx, key)
+	})
+}
+
+// to send one command per key by the read policy, and map the values by the
+// keys. A key failed takes its error as the value, and the first error is
+// returned.
+func (this *RedisCluster) inspectBatch(ctx context.Context, keys []string, fn func(ctx context.Context, pipe goredis.Pipeliner, key string) goredis.Cmder) (map[string]interface{}, error) {
+	batchRes := this.runBatch(ctx, keys, &batchSpec{
+		isWrite: false,
+		queue:   queuePerKey(fn),
+		parse: func(call *batchCall) []interface{} {
+			return []interface{}{inspectVal(call.Cmd)}
+		},
+	})
+
+	valMap := make(map[string]interface{}, len(batchRes.Results))
+	for _, one := range batchRes.Results {
+		if one.Err != nil {
+			valMap[one.Key] = one.Err
+		} else {
+			valMap[one.Key] = one.Val
+		}
+	}
+
+	return valMap, batchRes.Err()
+}
+
+// to get the value of an inspection, nil when the key is missing.
+func inspectVal(cmd goredis.Cmder) interface{} {
+	switch one := cmd.(type) {
+	case *goredis.DurationCmd:
+		// -2 is replied for a missing key.
+		if one.Val() == -2 {
+			return nil
+		}
+	case *goredis.StatusCmd:
+		if one.Val() == "none" {
+			return nil
+		}
+	}
+	return cmdVal(cmd)
+}
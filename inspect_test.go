@@ -0,0 +1,71 @@
+// Copyright (C) 2022
+// Author FrankXu <frankxury@gmail.com>
+// Build on 2026/10/18
+
+// The batch inspection test.
+
+package redis
+
+import (
+	"context"
+	goredis "github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestInspectVal(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	ttlCmd := goredis.NewDurationCmd(ctx, time.Second, "ttl", "a")
+	ttlCmd.SetVal(-2)
+	assert.Nil(inspectVal(ttlCmd))
+	ttlCmd.SetVal(-1)
+	assert.Equal(time.Duration(-1), inspectVal(ttlCmd))
+	ttlCmd.SetVal(30 * time.Second)
+	assert.Equal(30*time.Second, inspectVal(ttlCmd))
+
+	typeCmd := goredis.NewStatusCmd(ctx, "type", "a")
+	typeCmd.SetVal("none")
+	assert.Nil(inspectVal(typeCmd))
+	typeCmd.SetVal("hash")
+	assert.Equal("hash", inspectVal(typeCmd))
+
+	usageCmd := goredis.NewIntCmd(ctx, "memory", "usage", "a")
+	usageCmd.SetVal(56)
+	assert.Equal(int64(56), inspectVal(usageCmd))
+}
+
+func TestInspectBatch(t *testing.T) {
+	assert := assert.New(t)
+	cluster, fake := newFakeCluster(t, nil)
+	ctx := context.Background()
+
+	fake.Set("a", &fakeEntry{Type: "string", Str: "12", ExpireAt: time.Now().Add(time.Minute).UnixNano() / 1e6})
+	fake.Set("b", &fakeEntry{Type: "hash", Hash: map[string]string{"f": "1"}})
+
+	valMap, err := cluster.MType(ctx, "a", "b", "c", "a")
+	assert.Nil(err)
+	assert.Equal(map[string]interface{}{"a": "string", "b": "hash", "c": nil}, valMap)
+
+	// -1 for the key without ttl, nil for the missing one.
+	valMap, err = cluster.MTTL(ctx, "a", "b", "c")
+	assert.Nil(err)
+	assert.InDelta(time.Minute, valMap["a"], float64(time.Second))
+	assert.Equal(time.Duration(-1), valMap["b"])
+	assert.Nil(valMap["c"])
+
+	valMap, err = cluster.MPTTL(ctx, "a")
+	assert.Nil(err)
+	assert.InDelta(time.Minute, valMap["a"], float64(time.Second))
+
+	valMap, err = cluster.MObjectEncoding(ctx, "a", "b", "c")
+	assert.Nil(err)
+	assert.Equal(map[string]interface{}{"a": "int", "b": "listpack", "c": nil}, valMap)
+
+	valMap, err = cluster.MMemoryUsage(ctx, "a", "c")
+	assert.Nil(err)
+	assert.True(valMap["a"].(int64) > 0)
+	assert.Nil(valMap["c"])
+}
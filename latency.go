@@ -0,0 +1,89 @@
+// Copyright (C) 2022
+// Author FrankXu <frankxury@gmail.com>
+// Build on 2026/10/18
+
+// The PING latency monitor of the nodes.
+
+package redis
+
+import (
+	"context"
+	"time"
+)
+
+const (
+	LATENCY_CHECK_INTERVAL = 10 * time.Second
+	LATENCY_PROBE_TIMES    = 3
+)
+
+// to get the read policy of the call, the latency monitor is started on the
+// first use of the latency policy.
+func (this *RedisCluster) getReadPolicy(ctx context.Context) string {
+	policy := getBatchOptions(ctx).ReadPolicy
+	if policy == READ_FROM_DEFAULT {
+		policy = this.clientFactory.defaultReadPolicy()
+	}
+	if policy == READ_FROM_LATENCY {
+		this.startLatencyMonitor()
+	}
+	return policy
+}
+
+// to measure the nodes at once and then by LATENCY_CHECK_INTERVAL until the
+// cluster is closed.
+func (this *RedisCluster) startLatencyMonitor() {
+	this.latencyOnce.Do(func() {
+		go func() {
+			ticker := time.NewTicker(LATENCY_CHECK_INTERVAL)
+			defer ticker.Stop()
+
+			for {
+				this.measureLatencies()
+
+				select {
+				case <-ticker.C:
+				case <-this.closeCh:
+					return
+				}
+			}
+		}()
+	})
+}
+
+func (this *RedisCluster) measureLatencies() {
+	snap := this.nodes.Snapshot()
+	if snap == nil {
+		return
+	}
+
+	for _, node := range snap.allNodes() {
+		select {
+		case <-this.closeCh:
+			return
+		default:
+		}
+		if !node.IsAvailable() {
+			continue
+		}
+
+		addr := this.clientFactory.getClientKey(node)
+		curClient, err := this.clientFactory.GetRedisClientByAddr(addr)
+		if err != nil {
+			this.clientFactory.latencies.Delete(addr)
+			continue
+		}
+
+		var total time.Duration
+		for i := 0; i < LATENCY_PROBE_TIMES && err == nil; i++ {
+			startAt := time.Now()
+			err = curClient.Ping(context.Background()).Err()
+			total += time.Since(startAt)
+		}
+
+		if err != nil {
+			this.clientFactory.latencies.Delete(addr)
+		} else {
+			this.clientFactory.SetLatency(addr, total/LATENCY_PROBE_TIMES)
+		}
+	}
+}
@@ -0,0 +1,156 @@
+// Copyright (C) 2022
+// Author FrankXu <frankxury@gmail.com>
+// Build on 2026/10/18
+
+// The MSetNX across the shards.
+
+package redis
+
+import (
+	"context"
+	"errors"
+	goredis "github.com/go-redis/redis/v8"
+	"time"
+)
+
+const (
+	// to set all the keys of a slot only when none of them exists, the keys
+	// existing are returned, so an empty reply means the keys are written.
+	// KEYS: the keys, ARGV[1]: the ttl in milliseconds, ARGV[2...]: the values.
+	LUA_MSETNX = `
+local blocked = {}
+for i, key in ipairs(KEYS) do
+	if redis.call('exists', key) == 1 then
+		blocked[#blocked + 1] = key
+	end
+end
+if #blocked > 0 then
+	return blocked
+end
+local ttl = tonumber(ARGV[1])
+for i, key in ipairs(KEYS) do
+	if ttl > 0 then
+		redis.call('set', key, ARGV[i + 1], 'px', ttl)
+	else
+		redis.call('set', key, ARGV[i + 1])
+	end
+end
+return blocked`
+
+	// to delete the keys still holding the values written, the keys changed
+	// by others since are kept, even the ones turned into another type.
+	// KEYS: the keys, ARGV: the values.
+	LUA_DEL_IF_EQUAL = `
+local deleted = 0
+for i, key in ipairs(KEYS) do
+	if redis.call('type', key)['ok'] == 'string' and redis.call('get', key) == ARGV[i] then
+		deleted = deleted + redis.call('del', key)
+	end
+end
+return deleted`
+)
+
+// MSetNXResult tells per key whether it was written, true only when all the
+// keys were written.
+type MSetNXResult struct {
+	*BatchResult
+
+	IsSet       bool
+	BlockedKeys []string // the keys existing, which blocked the write.
+}
+
+// MSetNX sets all the key value pairs only if none of the keys exists, the
+// value is true when they are written.
+func (this *RedisCluster) MSetNX(ctx context.Context, dur time.Duration, values ...interface{}) *goredis.BoolCmd {
+	cmdKeys := append([]interface{}{"msetnx"}, values...)
+	result := goredis.NewBoolCmd(ctx, cmdKeys...)
+
+	nxRes := this.MSetNXWithResult(ctx, dur, values...)
+	result.SetVal(nxRes.IsSet)
+	result.SetErr(nxRes.Err())
+
+	return result
+}
+
+// MSetNXWithResult sets all the keys only if none of them exists across the
+// shards. Every slot is checked and set by one script, and the slots written
+// are rolled back when a key of another slot exists or fails.
+func (this *RedisCluster) MSetNXWithResult(ctx context.Context, dur time.Duration, values ...interface{}) *MSetNXResult {
+	helper := NewRedisHelper()
+	if len(values) == 0 || len(values)%2 != 0 {
+		return &MSetNXResult{BatchResult: newBatchErrResult(errors.New("The values should be the key value pairs."))}
+	}
+	keys, keyValMap, err := helper.GetKeysInPairInfs(values)
+	if err != nil {
+		return &MSetNXResult{BatchResult: newBatchErrResult(err)}
+	}
+
+	nxRes := &MSetNXResult{BlockedKeys: []string{}}
+	nxRes.BatchResult = this.runBatch(ctx, keys, &batchSpec{
+		isWrite: true,
+		queue: queuePerSlot(func(ctx context.Context, pipe goredis.Pipeliner, keys []string) goredis.Cmder {
+			args := []interface{}{formatExpireTTL(dur, time.Millisecond)}
+			for _, key := range keys {
+				args = append(args, keyValMap[key])
+			}
+			return pipe.Eval(ctx, LUA_MSETNX, keys, args...)
+		}),
+		sizeOf: func(key string) int {
+			return len(key) + helper.SizeOf(keyValMap[key])
+		},
+		parse: func(call *batchCall) []interface{} {
+			blocked, _ := call.Cmd.(*goredis.Cmd).Val().([]interface{})
+			vals := make([]interface{}, len(call.Keys))
+			for i := range vals {
+				vals[i] = len(blocked) == 0
+			}
+			return vals
+		},
+		onCall: func(call *batchCall) {
+			blocked, _ := call.Cmd.(*goredis.Cmd).Val().([]interface{})
+			for _, one := range blocked {
+				if key, isOk := one.(string); isOk {
+					nxRes.BlockedKeys = append(nxRes.BlockedKeys, key)
+				}
+			}
+		},
+	})
+
+	writtenKeys := []string{}
+	for _, key := range nxRes.UniqueKeys() {
+		if isSet, _ := nxRes.Get(key).Val.(bool); isSet {
+			writtenKeys = append(writtenKeys, key)
+		}
+	}
+
+	nxRes.IsSet = len(writtenKeys) == len(nxRes.UniqueKeys())
+	if !nxRes.IsSet && len(writtenKeys) > 0 {
+		this.rollbackMSetNX(ctx, nxRes, writtenKeys, keyValMap)
+	}
+
+	return nxRes
+}
+
+// to delete the keys written by the MSetNX which has not been set entirely,
+// a key failed to roll back keeps its error and stays written.
+func (this *RedisCluster) rollbackMSetNX(ctx context.Context, nxRes *MSetNXResult, writtenKeys []string, keyValMap map[string]interface{}) {
+	rollbackRes := this.runBatch(ctx, writtenKeys, &batchSpec{
+		isWrite: true,
+		queue: queuePerSlot(func(ctx context.Context, pipe goredis.Pipeliner, keys []string) goredis.Cmder {
+			args := []interface{}{}
+			for _, key := range keys {
+				args = append(args, keyValMap[key])
+			}
+			return pipe.Eval(ctx, LUA_DEL_IF_EQUAL, keys, args...)
+		}),
+	})
+
+	for _, key := range writtenKeys {
+		keyRes := nxRes.Get(key)
+		if err := rollbackRes.Get(key).Err; err != nil {
+			keyRes.Err = err
+		} else {
+			keyRes.Val = false
+		}
+	}
+}
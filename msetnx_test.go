@@ -0,0 +1,107 @@
+// Copyright (C) 2022
+// Author FrankXu <frankxury@gmail.com>
+// Build on 2026/10/18
+
+// The MSetNX test.
+
+package redis
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestMSetNXWithResult(t *testing.T) {
+	assert := assert.New(t)
+	cluster := newTestCluster(t, nil)
+	ctx := context.Background()
+
+	result := cluster.MSetNXWithResult(ctx, 0, "a", "1", "b")
+	assert.False(result.IsSet)
+	assert.NotNil(result.Err())
+
+	// every key fails on its unavailable master, so nothing is written.
+	result = cluster.MSetNXWithResult(ctx, 0, "a", "1", "b", "2")
+	assert.False(result.IsSet)
+	assert.NotNil(result.Err())
+	assert.Equal([]string{}, result.BlockedKeys)
+	assert.Equal([]string{"a", "b"}, result.FailedKeys())
+
+	cmd := cluster.MSetNX(ctx, 0, "a", "1")
+	assert.False(cmd.Val())
+	assert.NotNil(cmd.Err())
+}
+
+func TestMSetNXOnFake(t *testing.T) {
+	assert := assert.New(t)
+	cluster, fake := newFakeCluster(t, nil)
+	ctx := context.Background()
+
+	// all the keys are written with the ttl.
+	result := cluster.MSetNXWithResult(ctx, time.Minute, "a", "1", "b", "2", "{a}c", "3")
+	assert.Nil(result.Err())
+	assert.True(result.IsSet)
+	assert.Equal([]string{}, result.BlockedKeys)
+	for key, val := range map[string]string{"a": "1", "b": "2", "{a}c": "3"} {
+		assert.Equal(val, fake.Get(key).Str)
+		assert.True(fake.Get(key).ExpireAt > 0)
+		assert.Equal(true, result.Get(key).Val)
+	}
+
+	// "b" blocks its slot, and the slots written are rolled back.
+	fake.Set("a", nil)
+	fake.Set("{a}c", nil)
+	result = cluster.MSetNXWithResult(ctx, 0, "a", "10", "b", "20", "{a}c", "30", "{b}d", "40")
+	assert.Nil(result.Err())
+	assert.False(result.IsSet)
+	assert.Equal([]string{"b"}, result.BlockedKeys)
+	assert.Nil(fake.Get("a"))
+	assert.Nil(fake.Get("{a}c"))
+	assert.Equal("2", fake.Get("b").Str)
+	assert.Nil(fake.Get("{b}d"))
+	for _, key := range []string{"a", "b", "{a}c", "{b}d"} {
+		assert.Equal(false, result.Get(key).Val)
+	}
+
+	assert.False(cluster.MSetNX(ctx, 0, "b", "1").Val())
+	assert.True(cluster.MSetNX(ctx, 0, "e", "1").Val())
+
+	// a ttl less than 1ms is written as 1ms, not as no ttl.
+	result = cluster.MSetNXWithResult(ctx, 500*time.Microsecond, "f", "1", "g", "2")
+	assert.Nil(result.Err())
+	assert.True(result.IsSet)
+	time.Sleep(5 * time.Millisecond)
+	assert.Nil(fake.Get("f"))
+	assert.Nil(fake.Get("g"))
+}
+
+func TestRollbackMSetNX(t *testing.T) {
+	assert := assert.New(t)
+	cluster, fake := newFakeCluster(t, nil)
+	ctx := context.Background()
+
+	keys := []string{"{a}1", "{a}2", "{a}3", "b"}
+	keyValMap := map[string]interface{}{"{a}1": "1", "{a}2": "2", "{a}3": 3, "b": "4"}
+	nxRes := &MSetNXResult{BatchResult: newBatchResult(keys)}
+	for _, key := range keys {
+		nxRes.Get(key).Val = true
+	}
+
+	// "{a}2" was turned into a hash and "b" rewritten by others since.
+	fake.Set("{a}1", &fakeEntry{Type: "string", Str: "1"})
+	fake.Set("{a}2", &fakeEntry{Type: "hash", Hash: map[string]string{"f": "2"}})
+	fake.Set("{a}3", &fakeEntry{Type: "string", Str: "3"})
+	fake.Set("b", &fakeEntry{Type: "string", Str: "5"})
+
+	cluster.rollbackMSetNX(ctx, nxRes, keys, keyValMap)
+	assert.Nil(nxRes.Err())
+	assert.Nil(fake.Get("{a}1"))
+	assert.Equal("hash", fake.Get("{a}2").Type)
+	assert.Nil(fake.Get("{a}3"))
+	assert.Equal("5", fake.Get("b").Str)
+	for _, key := range keys {
+		assert.Equal(false, nxRes.Get(key).Val)
+	}
+}
@@ -0,0 +1,101 @@
+// Copyright (C) 2022
+// Author FrankXu <frankxury@gmail.com>
+// Build on 2026/10/18
+
+// The extra options of the redis cluster.
+
+package redis
+
+import (
+	"context"
+	"time"
+)
+
+const (
+	READ_FROM_DEFAULT = ""        // follow ReadOnly, RouteByLatency and RouteRandomly.
+	READ_FROM_MASTER  = "master"  // the master only.
+	READ_FROM_REPLICA = "replica" // a random replica, the master when none is available.
+	READ_FROM_RANDOM  = "random"  // a random node among the master and the replicas.
+	READ_FROM_LATENCY = "latency" // the node with the lowest PING latency.
+)
+
+// ExtOptions are the options of the features built on top of go-redis,
+// the zero value keeps the default behaviours.
+type ExtOptions struct {
+	// The interval of the background topology refresh, 0 disables it.
+	RefreshInterval time.Duration
+
+	// Called in order for every change found by a topology refresh.
+	OnTopologyEvent func(event *TopologyEvent)
+
+	// Where the topology is loaded from, support: auto, shards, nodes or slots.
+	// The auto selection uses the first one supported by the server.
+	TopologySource string
+
+	// To dial the nodes by their announced hostnames instead of their ips,
+	// the nodes without a hostname are still dialed by ip.
+	PreferHostname bool
+
+	// Consulted first by the batch reads not bound to the master, like the
+	// ZoneAffinitySelector, nil keeps the read policy only.
+	ReplicaSelector ReplicaSelector
+
+	// The max number of the nodes a batch command sends to at a time,
+	// 0 means MAX_COUCUR.
+	MaxConcurrency int
+
+	// The default chunk limits of the batch commands, see BatchOptions.
+	MaxKeysPerPipeline  int
+	MaxBytesPerPipeline int
+
+	// To delete the source of a cross slot rename by a script only when it
+	// is unchanged since its dump, otherwise the dest is rolled back.
+	GuardKeyMoves bool
+}
+
+func (this *ExtOptions) init() *ExtOptions {
+	newOpts := &ExtOptions{}
+	if this != nil {
+		*newOpts = *this
+	}
+	return newOpts
+}
+
+// BatchOptions are the per call options of the batch commands, they are
+// passed by the context.
+type BatchOptions struct {
+	// How the reads select the node, the writes always go to the master.
+	ReadPolicy string
+
+	// The max number of the nodes sent to at a time, 0 means the
+	// MaxConcurrency of the ExtOptions.
+	MaxConcurrency int
+
+	// To cancel the requests in flight and return on the first hard error,
+	// the misses and the redirections are not hard errors.
+	FailFast bool
+
+	// To split the keys of one node into the pipelines holding at most
+	// MaxKeysPerPipeline keys or MaxBytesPerPipeline bytes of keys and values,
+	// 0 means the limit of the ExtOptions, and no limit when both are 0.
+	MaxKeysPerPipeline  int
+	MaxBytesPerPipeline int
+
+	// The number of the chunks of one node sent at a time, 0 means one by one.
+	ChunkConcurrency int
+}
+
+type batchOptionsKey struct{}
+
+// WithBatchOptions returns a context carrying the batch options.
+func WithBatchOptions(ctx context.Context, opts *BatchOptions) context.Context {
+	return context.WithValue(ctx, batchOptionsKey{}, opts)
+}
+
+// to get the batch options of the call, never nil.
+func getBatchOptions(ctx context.Context) *BatchOptions {
+	if opts, isOk := ctx.Value(batchOptionsKey{}).(*BatchOptions); isOk && opts != nil {
+		return opts
+	}
+	return &BatchOptions{}
+}
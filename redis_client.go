@@ -11,45 +11,49 @@ import (
 	"errors"
 	"fmt"
 	goredis "github.com/go-redis/redis/v8"
-	"math/rand"
 	"sync"
 	"time"
 )
 
 type RedisClientFactory struct {
-	store   sync.Map
-	options *goredis.ClusterOptions
+	store     sync.Map
+	latencies sync.Map // addr => time.Duration
+	options   *goredis.ClusterOptions
+
+	// to dial the announced hostnames instead of the ips.
+	preferHostname bool
+	// consulted first by the reads not bound to the master.
+	replicaSelector ReplicaSelector
+
+	// to refuse the new clients once closed, the clients are stored with the
+	// read lock held.
+	closeLock sync.RWMutex
+	isClosed  bool
 }
 
 type RedisClient struct {
 	*goredis.Client
 }
 
-var (
-	oneRedisClientFactory *RedisClientFactory
-)
-
 func NewRedisClient(op *goredis.Options) (*RedisClient, error) {
 	r := goredis.NewClient(op)
 	oneRedisClient := &RedisClient{r}
 	if _, err := oneRedisClient.Ping(context.Background()).Result(); err != nil {
+		r.Close()
 		return nil, err
 	}
 	return oneRedisClient, nil
 }
 
 func NewRedisClientFactory(op *goredis.ClusterOptions) *RedisClientFactory {
-	if oneRedisClientFactory == nil {
-		oneRedisClientFactory = &RedisClientFactory{
-			store:   sync.Map{},
-			options: op,
-		}
+	return &RedisClientFactory{
+		store:   sync.Map{},
+		options: op,
 	}
-	return oneRedisClientFactory
 }
 
 func (this *RedisClientFactory) getClientKey(node *redisNode) string {
-	return fmt.Sprintf("%s:%s", node.Ip, node.Port)
+	return node.DialAddr(this.preferHostname)
 }
 
 func (this *RedisClientFactory) CleanStore() {
@@ -59,10 +63,30 @@ func (this *RedisClientFactory) CleanStore() {
 	})
 }
 
-func (this *RedisClientFactory) getCurOptions(node *redisNode) *goredis.Options {
+// Close closes and removes all the cached node clients, no client is made
+// after.
+func (this *RedisClientFactory) Close() error {
+	this.closeLock.Lock()
+	this.isClosed = true
+	this.closeLock.Unlock()
+
+	var firstErr error
+	this.store.Range(func(k, v interface{}) bool {
+		if err := v.(*RedisClient).Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+		this.store.Delete(k)
+		return true
+	})
+	return firstErr
+}
+
+func (this *RedisClientFactory) getCurOptions(addr string) *goredis.Options {
 	return &goredis.Options{
-		Addr:         fmt.Sprintf("%s:%s", node.Ip, node.Port),
+		Addr:         addr,
+		Username:     this.options.Username,
 		Password:     this.options.Password,
+		TLSConfig:    this.options.TLSConfig,
 		DB:           0,
 		PoolSize:     this.options.PoolSize,
 		PoolTimeout:  this.options.PoolTimeout,
@@ -71,51 +95,192 @@ func (this *RedisClientFactory) getCurOptions(node *redisNode) *goredis.Options
 		WriteTimeout: this.options.WriteTimeout,
 		IdleTimeout:  this.options.IdleTimeout,
 		MaxRetries:   this.options.MaxRetries,
+		OnConnect:    this.onConnect,
+	}
+}
+
+// to enable the reads on the replicas, it is harmless on the masters.
+func (this *RedisClientFactory) onConnect(ctx context.Context, cn *goredis.Conn) error {
+	if err := cn.ReadOnly(ctx).Err(); err != nil {
+		return err
+	}
+	if this.options.OnConnect != nil {
+		return this.options.OnConnect(ctx, cn)
 	}
+	return nil
 }
 
 func (this *RedisClientFactory) GetRedisClient(nodeGroup *redisGroup, isWrite bool) (*RedisClient, error) {
+	if isWrite {
+		return this.GetRedisClientByPolicy(nodeGroup, READ_FROM_MASTER)
+	}
+	return this.GetRedisClientByPolicy(nodeGroup, READ_FROM_DEFAULT)
+}
+
+// GetRedisClientByPolicy selects the node of the group by the read policy,
+// the failed nodes and the nodes in handshake are skipped.
+func (this *RedisClientFactory) GetRedisClientByPolicy(nodeGroup *redisGroup, policy string) (*RedisClient, error) {
+	hitNode, err := this.selectNode(nodeGroup, policy)
+	if err != nil {
+		return nil, err
+	}
+	return this.GetRedisClientByAddr(this.getClientKey(hitNode))
+}
+
+func (this *RedisClientFactory) selectNode(nodeGroup *redisGroup, policy string) (*redisNode, error) {
+	if policy == READ_FROM_DEFAULT {
+		policy = this.defaultReadPolicy()
+	}
+
+	master := nodeGroup.master
+	if master != nil && !master.IsAvailable() {
+		master = nil
+	}
+	slaves := []*redisNode{}
+	for _, slave := range nodeGroup.slaves {
+		if slave.IsAvailable() {
+			slaves = append(slaves, slave)
+		}
+	}
+
 	// to select the slot node
 	var hitNode *redisNode
+	if policy != READ_FROM_MASTER && this.replicaSelector != nil {
+		if hitNode = this.selectByReplicaSelector(master, slaves); hitNode != nil {
+			return hitNode, nil
+		}
+	}
 
-	if isWrite {
-		hitNode = nodeGroup.master
-	} else {
-		allNodes := []*redisNode{}
-		allNodes = append(allNodes, nodeGroup.slaves...)
-		if !this.options.ReadOnly {
-			allNodes = append(allNodes, nodeGroup.master)
+	switch policy {
+	case READ_FROM_MASTER:
+		hitNode = master
+	case READ_FROM_REPLICA:
+		if hitNode = this.randomNode(slaves); hitNode == nil {
+			hitNode = master
+		}
+	case READ_FROM_RANDOM:
+		if master != nil {
+			slaves = append(slaves, master)
 		}
-		nodesLen := len(allNodes)
-		if nodesLen == 0 {
-		} else if nodesLen == 1 {
-			hitNode = allNodes[0]
-		} else {
-			// to get one node by rand.
-			r := rand.New(rand.NewSource(time.Now().UnixNano()))
-			hitNode = allNodes[r.Intn(nodesLen)]
+		hitNode = this.randomNode(slaves)
+	case READ_FROM_LATENCY:
+		if master != nil {
+			slaves = append(slaves, master)
 		}
+		hitNode = this.closestNode(slaves)
+	default:
+		return nil, fmt.Errorf("unknown read policy: %s", policy)
 	}
 
 	if hitNode == nil {
 		return nil, errors.New("redis nodes were empty.")
 	}
 
+	return hitNode, nil
+}
+
+// to follow the ReadOnly, RouteByLatency and RouteRandomly like go-redis.
+func (this *RedisClientFactory) defaultReadPolicy() string {
+	switch {
+	case this.options.RouteByLatency:
+		return READ_FROM_LATENCY
+	case this.options.RouteRandomly:
+		return READ_FROM_RANDOM
+	case this.options.ReadOnly:
+		return READ_FROM_REPLICA
+	}
+	return READ_FROM_MASTER
+}
+
+func (this *RedisClientFactory) randomNode(nodes []*redisNode) *redisNode {
+	nodesLen := len(nodes)
+	if nodesLen == 0 {
+		return nil
+	} else if nodesLen == 1 {
+		return nodes[0]
+	}
+
+	// to get one node by rand.
+	return nodes[randIntn(nodesLen)]
+}
+
+// to get the node with the lowest PING latency, the nodes never measured
+// are only chosen at random when there is no measurement at all.
+func (this *RedisClientFactory) closestNode(nodes []*redisNode) *redisNode {
+	var hitNode *redisNode
+	var minLatency time.Duration
+	for _, node := range nodes {
+		if latency, isExists := this.latencies.Load(this.getClientKey(node)); isExists {
+			if hitNode == nil || latency.(time.Duration) < minLatency {
+				hitNode = node
+				minLatency = latency.(time.Duration)
+			}
+		}
+	}
+
+	if hitNode == nil {
+		return this.randomNode(nodes)
+	}
+	return hitNode
+}
+
+func (this *RedisClientFactory) selectByReplicaSelector(master *redisNode, slaves []*redisNode) *redisNode {
+	nodeMap := map[string]*redisNode{}
+	toView := func(node *redisNode) *ReplicaNode {
+		nodeMap[node.Id] = node
+		view := &ReplicaNode{Id: node.Id, Addr: node.Addr(), Hostname: node.Hostname, IsMaster: node.Role == ROLE_MASTER}
+		if latency, isExists := this.latencies.Load(this.getClientKey(node)); isExists {
+			view.Latency = latency.(time.Duration)
+		}
+		return view
+	}
+
+	var masterView *ReplicaNode
+	if master != nil {
+		masterView = toView(master)
+	}
+	replicaViews := make([]*ReplicaNode, 0, len(slaves))
+	for _, slave := range slaves {
+		replicaViews = append(replicaViews, toView(slave))
+	}
+
+	if hitView := this.replicaSelector.Select(masterView, replicaViews); hitView != nil {
+		return nodeMap[hitView.Id]
+	}
+	return nil
+}
+
+// SetLatency records the PING latency of the node listening on the addr.
+func (this *RedisClientFactory) SetLatency(addr string, latency time.Duration) {
+	this.latencies.Store(addr, latency)
+}
+
+// GetRedisClientByAddr returns the client of the node which listens on the addr,
+// it is used to follow the redirections.
+func (this *RedisClientFactory) GetRedisClientByAddr(addr string) (*RedisClient, error) {
 	// to load the redis client by key.
-	hitKey := this.getClientKey(hitNode)
-	if rcInf, isExists := this.store.Load(hitKey); isExists {
+	if rcInf, isExists := this.store.Load(addr); isExists {
 		return rcInf.(*RedisClient), nil
 	}
 
 	// new a group client object.
 	var newClient *RedisClient
 	var err error
-	if newClient, err = NewRedisClient(this.getCurOptions(hitNode)); err != nil {
+	if newClient, err = NewRedisClient(this.getCurOptions(addr)); err != nil {
 		return newClient, err
 	}
 
-	// cache the new client object.
-	this.store.Store(hitKey, newClient)
+	// cache the new client object, keep the one stored first by a concurrent caller.
+	this.closeLock.RLock()
+	defer this.closeLock.RUnlock()
+	if this.isClosed {
+		newClient.Close()
+		return nil, goredis.ErrClosed
+	}
+	if rcInf, isLoaded := this.store.LoadOrStore(addr, newClient); isLoaded {
+		newClient.Close()
+		return rcInf.(*RedisClient), nil
+	}
 
 	return newClient, nil
 }
@@ -0,0 +1,89 @@
+// Copyright (C) 2022
+// Author FrankXu <frankxury@gmail.com>
+// Build on 2026/10/18
+
+// The redis client test.
+
+package redis
+
+import (
+	goredis "github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func newTestGroup(t *testing.T) *redisGroup {
+	nodes, err := NewRedisNodes("aaaa 10.0.0.1:6379@16379 master - 0 0 1 connected 0-16383\n" +
+		"bbbb 10.0.0.2:6379@16379 slave aaaa 0 0 1 connected\n" +
+		"cccc 10.0.0.3:6379@16379 slave,fail aaaa 0 0 1 connected\n" +
+		"dddd 10.0.0.4:6379@16379 slave aaaa 0 0 1 connected\n")
+	assert.Nil(t, err)
+	group, _ := nodes.FindNodeByCRC16Val(0)
+	return group
+}
+
+func TestDefaultReadPolicy(t *testing.T) {
+	testCases := []struct {
+		Options goredis.ClusterOptions
+		Policy  string
+	}{
+		{goredis.ClusterOptions{}, READ_FROM_MASTER},
+		{goredis.ClusterOptions{ReadOnly: true}, READ_FROM_REPLICA},
+		{goredis.ClusterOptions{RouteRandomly: true}, READ_FROM_RANDOM},
+		{goredis.ClusterOptions{RouteRandomly: true, RouteByLatency: true}, READ_FROM_LATENCY},
+	}
+
+	assert := assert.New(t)
+	for _, test := range testCases {
+		opts := test.Options
+		assert.Equal(test.Policy, NewRedisClientFactory(&opts).defaultReadPolicy(), "%#v failed.", test.Options)
+	}
+}
+
+func TestSelectNode(t *testing.T) {
+	assert := assert.New(t)
+	group := newTestGroup(t)
+	factory := NewRedisClientFactory(&goredis.ClusterOptions{})
+
+	node, err := factory.selectNode(group, READ_FROM_DEFAULT)
+	assert.Nil(err)
+	assert.Equal("aaaa", node.Id)
+
+	for i := 0; i < 20; i++ {
+		node, _ = factory.selectNode(group, READ_FROM_REPLICA)
+		assert.Contains([]string{"bbbb", "dddd"}, node.Id)
+		node, _ = factory.selectNode(group, READ_FROM_RANDOM)
+		assert.Contains([]string{"aaaa", "bbbb", "dddd"}, node.Id)
+	}
+
+	factory.SetLatency("10.0.0.1:6379", 3*time.Millisecond)
+	factory.SetLatency("10.0.0.2:6379", 2*time.Millisecond)
+	factory.SetLatency("10.0.0.3:6379", time.Millisecond)
+	node, _ = factory.selectNode(group, READ_FROM_LATENCY)
+	assert.Equal("bbbb", node.Id)
+
+	_, err = factory.selectNode(group, "nearest")
+	assert.NotNil(err)
+}
+
+func TestFactoryClose(t *testing.T) {
+	assert := assert.New(t)
+	cluster, fake := newFakeCluster(t, nil)
+
+	_, err := cluster.clientFactory.GetRedisClientByAddr(fake.nodes[0].addr())
+	assert.Nil(err)
+
+	// no client is made after the close, even by the latency probes.
+	assert.Nil(cluster.Close())
+	_, err = cluster.clientFactory.GetRedisClientByAddr(fake.nodes[0].addr())
+	assert.Equal(goredis.ErrClosed, err)
+	cluster.measureLatencies()
+
+	count := 0
+	cluster.clientFactory.store.Range(func(k, v interface{}) bool {
+		count++
+		return true
+	})
+	assert.Equal(0, count)
+}
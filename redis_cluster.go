@@ -11,6 +11,8 @@ import (
 	"errors"
 	goredis "github.com/go-redis/redis/v8"
 	"log"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -23,9 +25,22 @@ const (
 type RedisCluster struct {
 	*goredis.ClusterClient
 
-	clusterInfo *clusterInfo
-	nodes       *redisNodes
-	curContext  context.Context
+	clusterInfo   *clusterInfo
+	nodes         *redisNodes
+	clientFactory *RedisClientFactory
+	curContext    context.Context
+	extOpts       *ExtOptions
+	notifier      *topologyNotifier
+
+	// nil until the auto selection finds a working source.
+	topologySource topologySource
+
+	refreshLock      sync.Mutex
+	lastRefreshAt    int64 // unix nano of the last full refresh.
+	isRefreshPending int32
+	closeCh          chan struct{}
+	closeOnce        sync.Once
+	latencyOnce      sync.Once
 }
 
 type hitKeysItem struct {
@@ -36,23 +51,68 @@ type hitKeysItem struct {
 type ClusterOptions = goredis.ClusterOptions
 
 func NewClusterClient(ctx context.Context, opt *ClusterOptions) (*RedisCluster, error) {
+	return NewClusterClientWithExt(ctx, opt, nil)
+}
+
+// NewClusterClientWithExt news the cluster client with the extra options,
+// a nil ext keeps the default behaviours.
+func NewClusterClientWithExt(ctx context.Context, opt *ClusterOptions, ext *ExtOptions) (*RedisCluster, error) {
+	source, err := newTopologySource(ext.init().TopologySource)
+	if err != nil {
+		return nil, err
+	}
+
 	core := goredis.NewClusterClient(opt)
 
-	obj := &RedisCluster{core, nil, nil, ctx}
+	// every cluster object owns its topology and node clients.
+	obj := &RedisCluster{
+		ClusterClient: core,
+		nodes:         &redisNodes{},
+		clientFactory: NewRedisClientFactory(opt),
+		curContext:    ctx,
+		extOpts:       ext.init(),
+		notifier:      newTopologyNotifier(),
+		closeCh:       make(chan struct{}),
+
+		topologySource: source,
+	}
+	obj.clientFactory.preferHostname = obj.extOpts.PreferHostname
+	obj.clientFactory.replicaSelector = obj.extOpts.ReplicaSelector
 	if err := obj.initClustInfo(ctx); err != nil {
+		obj.Close()
 		return nil, err
 	}
 
+	obj.startRefresher()
+	if obj.clientFactory.defaultReadPolicy() == READ_FROM_LATENCY {
+		obj.startLatencyMonitor()
+	}
+
 	return obj, nil
 }
 
+// Close closes the node clients and the cluster client.
+func (this *RedisCluster) Close() error {
+	this.closeOnce.Do(func() {
+		close(this.closeCh)
+		this.notifier.closeAll()
+	})
+	factoryErr := this.clientFactory.Close()
+	if err := this.ClusterClient.Close(); err != nil {
+		return err
+	}
+	return factoryErr
+}
+
 func (this *RedisCluster) initClustInfo(ctx context.Context) error {
 	var clusterInfo *clusterInfo
-	var nodes *redisNodes
+
+	this.refreshLock.Lock()
+	defer this.refreshLock.Unlock()
 
 	if ci, err := this.ClusterInfo(ctx).Result(); err != nil {
 		return err
-	} else if cn, err := this.ClusterNodes(ctx).Result(); err != nil {
+	} else if nodes, err := this.loadTopology(ctx); err != nil {
 		return err
 	} else {
 		clusterInfo = NewClusterInfo(ci)
@@ -60,24 +120,40 @@ func (this *RedisCluster) initClustInfo(ctx context.Context) error {
 			return errors.New("cluster is not OK")
 		}
 
-		if nodes, err = NewRedisNodes(cn); err != nil {
-			return err
-		}
+		oldSnap := this.nodes.LoadedSnapshot()
+		this.nodes.SetNodes(nodes)
 
 		this.clusterInfo = clusterInfo
-		this.nodes = nodes
+		atomic.StoreInt64(&this.lastRefreshAt, time.Now().UnixNano())
+		this.publishEvents(diffSnapshots(oldSnap, this.nodes.Snapshot()))
 	}
 
 	return nil
 }
 
-func (this *RedisCluster) getKeyNodesMap(keys []string) map[string]*hitKeysItem {
+// getKeyNodesMap groups the keys by master, and returns the keys whose slot
+// has no node. The topology is then reloaded in the background, the request
+// is never blocked by the reload.
+func (this *RedisCluster) getKeyNodesMap(keys []string) (map[string]*hitKeysItem, []string) {
 	keyNodesMap := map[string]*hitKeysItem{}
+	missedKeys := this.routeKeys(this.nodes.Snapshot(), keys, keyNodesMap)
+
+	if len(missedKeys) > 0 {
+		log.Printf("The slot node has not been found for %d keys, the first key: '%s'", len(missedKeys), missedKeys[0])
+		this.scheduleRefresh()
+	}
+
+	return keyNodesMap, missedKeys
+}
+
+// to add the keys to their groups in the map, and return the missed keys.
+func (this *RedisCluster) routeKeys(snap *nodesSnapshot, keys []string, keyNodesMap map[string]*hitKeysItem) []string {
 	crc16Handle := NewCRC16()
+	missedKeys := []string{}
 
 	for _, key := range keys {
 		curCRC16Val := crc16Handle.HashSlot(key)
-		if hitNodeGP, isFound := this.nodes.FindNodeByCRC16Val(curCRC16Val); isFound {
+		if hitNodeGP, isFound := snap.FindNodeByCRC16Val(curCRC16Val); isFound {
 			if _, isExists := keyNodesMap[hitNodeGP.master.Id]; !isExists {
 				keyNodesMap[hitNodeGP.master.Id] = &hitKeysItem{
 					Keys:      []string{},
@@ -87,12 +163,11 @@ func (this *RedisCluster) getKeyNodesMap(keys []string) map[string]*hitKeysItem
 
 			keyNodesMap[hitNodeGP.master.Id].Keys = append(keyNodesMap[hitNodeGP.master.Id].Keys, key)
 		} else {
-			log.Printf("The slot node has not been found for the key '%s', spot: %d", key, curCRC16Val)
-			this.initClustInfo(this.curContext)
+			missedKeys = append(missedKeys, key)
 		}
 	}
 
-	return keyNodesMap
+	return missedKeys
 }
 
 func (this *RedisCluster) getHitGroupInMap(key string, hitMap map[string]*hitKeysItem) (*redisGroup, error) {
@@ -125,85 +200,56 @@ func (this *RedisCluster) strArr2InfArr(keys []string) []interface{} {
 
 // Refactor the Del method.
 func (this *RedisCluster) Del(ctx context.Context, keys ...string) *goredis.IntCmd {
-	keyInfs := append([]interface{}{"del"}, this.strArr2InfArr(keys)...)
-	triedTimes := 0
-
-TryAgain:
-
-	// init the params.
-	keyNodesMap := this.getKeyNodesMap(keys)
-	mapLen := len(keyNodesMap)
-	redisFactory := NewRedisClientFactory(this.Options())
-
-	type curResultModel struct {
-		Err error
-		Val int64
-	}
-	var resCh chan *curResultModel = make(chan *curResultModel, mapLen)
-
-	// To del by group.
-	for _, node := range keyNodesMap {
-		go func(resCh chan *curResultModel, curNode *hitKeysItem) {
-			curRes := &curResultModel{}
-			curClient, err := redisFactory.GetRedisClient(curNode.HitNodeGP, true)
-			if err != nil {
-				curRes.Err = err
-				resCh <- curRes
-				return
-			}
-
-			curPipe := curClient.Pipeline()
-			resArr := []*goredis.IntCmd{}
-			for _, curKey := range curNode.Keys {
-				res := curPipe.Del(ctx, curKey)
-				resArr = append(resArr, res)
-			}
-
-			_, err = curPipe.Exec(ctx)
-			if err != nil {
-				curRes.Err = err
-				resCh <- curRes
-				return
-			}
+	// one DEL per slot, the count of the keys deleted is kept on an error.
+	return this.sumBatch(ctx, "del", keys, &batchSpec{
+		isWrite: true,
+		queue: queuePerSlot(func(ctx context.Context, pipe goredis.Pipeliner, keys []string) goredis.Cmder {
+			return pipe.Del(ctx, keys...)
+		}),
+	})
+}
 
-			for _, one := range resArr {
-				if err = one.Err(); err != nil {
-					curRes.Err = one.Err()
-					resCh <- curRes
-					return
-				}
-				curRes.Val += one.Val()
-			}
+// to run the batch and sum the replies of the calls, like the counts of the
+// DELs, the sum of the calls succeeded is kept on an error.
+func (this *RedisCluster) sumBatch(ctx context.Context, cmdName string, keys []string, spec *batchSpec) *goredis.IntCmd {
+	keyInfs := append([]interface{}{cmdName}, this.strArr2InfArr(keys)...)
+	result := goredis.NewIntCmd(ctx, keyInfs...)
 
-			resCh <- curRes
-		}(resCh, node)
+	var totalVal int64 = 0
+	spec.onCall = func(call *batchCall) {
+		totalVal += this.countOf(call.Cmd)
 	}
+	batchRes := this.runBatch(ctx, keys, spec)
 
-	result := goredis.NewIntCmd(ctx, keyInfs...)
-	var totalVal int64 = 0
+	result.SetVal(totalVal)
+	result.SetErr(batchRes.Err())
 
-	// merge the results.
-	for i := 0; i < mapLen; i++ {
-		curRes := <-resCh
-		if curRes.Err != nil {
-			if NewRedisHelper().IsMovedError(curRes.Err) {
-				this.initClustInfo(this.ClusterClient.Context())
-				if triedTimes < 3 {
-					triedTimes += 1
-					goto TryAgain
-				}
-			}
+	return result
+}
 
-			result.SetErr(curRes.Err)
-			return result
-		} else {
-			totalVal += curRes.Val
+// to get the count replied, a true is counted as 1.
+func (this *RedisCluster) countOf(cmd goredis.Cmder) int64 {
+	switch one := cmd.(type) {
+	case *goredis.IntCmd:
+		return one.Val()
+	case *goredis.BoolCmd:
+		if one.Val() {
+			return 1
 		}
 	}
+	return 0
+}
 
-	result.SetVal(totalVal)
-
-	return result
+// DelWithResult deletes the keys and records per key whether it was deleted (1 or 0),
+// it sends one DEL per key since a DEL of many keys only replies the total.
+func (this *RedisCluster) DelWithResult(ctx context.Context, keys ...string) *BatchResult {
+	// To del by group.
+	return this.runBatch(ctx, keys, &batchSpec{
+		isWrite: true,
+		queue: queuePerKey(func(ctx context.Context, pipe goredis.Pipeliner, key string) goredis.Cmder {
+			return pipe.Del(ctx, key)
+		}),
+	})
 }
 
 func (this *RedisCluster) Exists(ctx context.Context, keys ...string) *goredis.IntCmd {
@@ -211,260 +257,156 @@ func (this *RedisCluster) Exists(ctx context.Context, keys ...string) *goredis.I
 		return this.ClusterClient.Exists(ctx, keys...)
 	}
 
-	triedTimes := 0
-
-TryAgain:
-
-	keyNodesMap := this.getKeyNodesMap(keys)
-
-	redisFactory := NewRedisClientFactory(this.Options())
-
-	keyInfs := append([]interface{}{"exists"}, this.strArr2InfArr(keys)...)
-	mapLen := len(keyNodesMap)
-
-	type curResultModel struct {
-		Err error
-		Val int64
-	}
-	var resCh chan *curResultModel = make(chan *curResultModel, mapLen)
-
-	for _, node := range keyNodesMap {
-		go func(resCh chan *curResultModel, curNode *hitKeysItem) {
-			curRes := &curResultModel{}
-			curClient, err := redisFactory.GetRedisClient(curNode.HitNodeGP, true)
-			if err != nil {
-				curRes.Err = err
-				resCh <- curRes
-				return
-			}
-
-			curPipe := curClient.Pipeline()
-			resArr := []*goredis.IntCmd{}
-			for _, curKey := range curNode.Keys {
-				res := curPipe.Exists(ctx, curKey)
-				resArr = append(resArr, res)
-			}
+	return this.sumBatch(ctx, "exists", keys, &batchSpec{
+		isWrite: false,
+		queue: queuePerSlotRepeated(keys, func(ctx context.Context, pipe goredis.Pipeliner, keys []string) goredis.Cmder {
+			return pipe.Exists(ctx, keys...)
+		}),
+	})
+}
 
-			_, err = curPipe.Exec(ctx)
-			if err != nil && err != goredis.Nil {
-				curRes.Err = err
-				resCh <- curRes
-				return
-			}
+// ExistsWithResult records per key whether it exists (1 or 0), by one EXISTS per key.
+func (this *RedisCluster) ExistsWithResult(ctx context.Context, keys ...string) *BatchResult {
+	return this.runBatch(ctx, keys, &batchSpec{
+		isWrite: false,
+		queue: queuePerKey(func(ctx context.Context, pipe goredis.Pipeliner, key string) goredis.Cmder {
+			return pipe.Exists(ctx, key)
+		}),
+	})
+}
 
-			var curTotal int64 = 0
-			for _, one := range resArr {
-				curTotal += one.Val()
-			}
+// Unlink removes the keys like Del, the memory is reclaimed in the background.
+func (this *RedisCluster) Unlink(ctx context.Context, keys ...string) *goredis.IntCmd {
+	return this.sumBatch(ctx, "unlink", keys, &batchSpec{
+		isWrite: true,
+		queue: queuePerSlot(func(ctx context.Context, pipe goredis.Pipeliner, keys []string) goredis.Cmder {
+			return pipe.Unlink(ctx, keys...)
+		}),
+	})
+}
 
-			curRes.Val = curTotal
-			resCh <- curRes
-		}(resCh, node)
-	}
+// Touch alters the last access time of the keys, and counts the keys existing
+// as many times as given, like TOUCH.
+func (this *RedisCluster) Touch(ctx context.Context, keys ...string) *goredis.IntCmd {
+	return this.sumBatch(ctx, "touch", keys, &batchSpec{
+		isWrite: true,
+		queue: queuePerSlotRepeated(keys, func(ctx context.Context, pipe goredis.Pipeliner, keys []string) goredis.Cmder {
+			return pipe.Touch(ctx, keys...)
+		}),
+	})
+}
 
-	result := goredis.NewIntCmd(ctx, keyInfs...)
-	var totalVal int64 = 0
+// MPersist removes the ttl of the keys, and counts the keys whose ttl was
+// removed. PERSIST takes one key, so it is sent per key.
+func (this *RedisCluster) MPersist(ctx context.Context, keys ...string) *goredis.IntCmd {
+	return this.sumBatch(ctx, "mpersist", keys, &batchSpec{
+		isWrite: true,
+		queue: queuePerKey(func(ctx context.Context, pipe goredis.Pipeliner, key string) goredis.Cmder {
+			return pipe.Persist(ctx, key)
+		}),
+	})
+}
 
-	for i := 0; i < mapLen; i++ {
-		if curRes := <-resCh; curRes.Err != nil {
-			if NewRedisHelper().IsMovedError(curRes.Err) {
-				this.initClustInfo(this.ClusterClient.Context())
-				if triedTimes < 3 {
-					triedTimes += 1
-					goto TryAgain
-				}
-			}
+// Refactor the MSet method.
+func (this *RedisCluster) MSet(ctx context.Context, dur time.Duration, values ...interface{}) *goredis.StatusCmd {
+	cmdKeys := append([]interface{}{"mset"}, values...)
+	result := goredis.NewStatusCmd(ctx, cmdKeys...)
 
-			result.SetErr(curRes.Err)
-			return result
-		} else {
-			totalVal += curRes.Val
-		}
+	// merge the results, any key failed fails the command.
+	batchRes := this.MSetWithResult(ctx, dur, values...)
+	if err := batchRes.Err(); err != nil {
+		result.SetErr(err)
+		return result
 	}
 
-	result.SetVal(totalVal)
+	result.SetVal("OK")
 
 	return result
 }
 
-// Refactor the MSet method.
-func (this *RedisCluster) MSet(ctx context.Context, dur time.Duration, values ...interface{}) *goredis.StatusCmd {
-	cmdKeys := append([]interface{}{"mset"}, values...)
-	getError := func(err error) *goredis.StatusCmd {
-		sCms := goredis.NewStatusCmd(ctx, cmdKeys...)
-		sCms.SetErr(err)
-		return sCms
-	}
-
+// MSetWithResult sets the key value pairs with the ttl, and records per key
+// whether the write landed ("OK") or its error.
+func (this *RedisCluster) MSetWithResult(ctx context.Context, dur time.Duration, values ...interface{}) *BatchResult {
 	// init params.
 	var keys = []string{}
 	var keyValMap = map[string]interface{}{}
 	var err error
 	var helper = NewRedisHelper()
 	if keys, keyValMap, err = helper.GetKeysInPairInfs(values); err != nil {
-		return getError(err)
-	}
-
-	triedTimes := 0
-
-TryAgain:
-
-	keyNodesMap := this.getKeyNodesMap(keys)
-	mapLen := len(keyNodesMap)
-
-	type curResultModel struct {
-		Err error
-		Val string
+		return newBatchErrResult(err)
 	}
-	var resCh chan *curResultModel = make(chan *curResultModel, mapLen)
-
-	redisFactory := NewRedisClientFactory(this.Options())
-
-	// MSet by group Pipeline.
-	for _, node := range keyNodesMap {
-		go func(resCh chan *curResultModel, curNode *hitKeysItem) {
-			curRes := &curResultModel{}
-			curClient, err := redisFactory.GetRedisClient(curNode.HitNodeGP, true)
-			if err != nil {
-				curRes.Err = err
-				resCh <- curRes
-				return
-			}
 
-			curPipe := curClient.Pipeline()
-			resArr := []*goredis.StatusCmd{}
-			for _, curKey := range curNode.Keys {
-				resArr = append(resArr, curPipe.Set(ctx, curKey, keyValMap[curKey], dur))
-			}
-
-			_, err = curPipe.Exec(ctx)
-			if err != nil {
-				curRes.Err = err
-				resCh <- curRes
-				return
-			}
-
-			for _, one := range resArr {
-				if one.Val() != "OK" {
-					curRes.Val = "NO"
-					curRes.Err = one.Err()
-					resCh <- curRes
-					return
-				}
-			}
-
-			curRes.Val = "OK"
-			resCh <- curRes
-		}(resCh, node)
+	spec := &batchSpec{
+		isWrite: true,
+		sizeOf: func(key string) int {
+			return len(key) + helper.SizeOf(keyValMap[key])
+		},
 	}
 
-	// merge the results.
-	result := goredis.NewStatusCmd(ctx, cmdKeys)
-	var lastStatus = ""
-
-	for i := 0; i < mapLen; i++ {
-		if curRes := <-resCh; curRes.Err != nil {
-			if NewRedisHelper().IsMovedError(curRes.Err) {
-				this.initClustInfo(this.ClusterClient.Context())
-				if triedTimes < 3 {
-					triedTimes += 1
-					goto TryAgain
-				}
+	if dur > 0 {
+		// MSET has no ttl, so one SET per key.
+		spec.queue = queuePerKey(func(ctx context.Context, pipe goredis.Pipeliner, key string) goredis.Cmder {
+			return pipe.Set(ctx, key, keyValMap[key], dur)
+		})
+	} else {
+		// one MSET per slot.
+		spec.queue = queuePerSlot(func(ctx context.Context, pipe goredis.Pipeliner, keys []string) goredis.Cmder {
+			pairs := make([]interface{}, 0, len(keys)*2)
+			for _, key := range keys {
+				pairs = append(pairs, key, keyValMap[key])
 			}
-
-			result.SetErr(curRes.Err)
-			return result
-		} else {
-			lastStatus = curRes.Val
-			if curRes.Val != "OK" {
-				break
+			return pipe.MSet(ctx, pairs...)
+		})
+		spec.parse = func(call *batchCall) []interface{} {
+			vals := make([]interface{}, len(call.Keys))
+			for i := range vals {
+				vals[i] = call.Cmd.(*goredis.StatusCmd).Val()
 			}
+			return vals
 		}
 	}
 
-	result.SetVal(lastStatus)
-
-	return result
+	return this.runBatch(ctx, keys, spec)
 }
 
 // Refactor the MGet method.
 func (this *RedisCluster) MGet(ctx context.Context, keys ...string) *goredis.SliceCmd {
-	cmdKeys := append([]interface{}{"mget"}, this.strArr2InfArr(keys)...)
-
-	triedTimes := 0
-
-TryAgain:
-
-	// init params.
-	keyNodesMap := this.getKeyNodesMap(keys)
-	mapLen := len(keyNodesMap)
-
-	type curResultModel struct {
-		Err error
-		Val map[string]*goredis.StringCmd
-	}
-	var resCh chan *curResultModel = make(chan *curResultModel, mapLen)
-
-	redisFactory := NewRedisClientFactory(this.Options())
-
-	// MGet by group.
-	for _, node := range keyNodesMap {
-		go func(resCh chan *curResultModel, curNode *hitKeysItem) {
-			curRes := &curResultModel{}
-			curClient, err := redisFactory.GetRedisClient(curNode.HitNodeGP, true)
-			if err != nil {
-				curRes.Err = err
-				resCh <- curRes
-				return
-			}
-
-			curPipe := curClient.Pipeline()
-			resMap := map[string]*goredis.StringCmd{}
-			for _, curKey := range curNode.Keys {
-				resMap[curKey] = curPipe.Get(ctx, curKey)
-			}
-
-			_, err = curPipe.Exec(ctx)
-			if err != nil && err != goredis.Nil {
-				curRes.Err = err
-				resCh <- curRes
-				return
-			}
-
-			curRes.Val = resMap
-			resCh <- curRes
-		}(resCh, node)
-	}
-
-	// merge the results.
-	var vals = []interface{}{}
+	return this.toMGetCmd(ctx, keys, this.MGetWithResult(ctx, keys...))
+}
 
+// to merge the results like MGET: nil for a missing key, and the error in
+// place of the value for a key which failed, like an unroutable key.
+func (this *RedisCluster) toMGetCmd(ctx context.Context, keys []string, batchRes *BatchResult) *goredis.SliceCmd {
+	cmdKeys := append([]interface{}{"mget"}, this.strArr2InfArr(keys)...)
 	sCms := goredis.NewSliceCmd(ctx, cmdKeys...)
-	resultMap := map[string]*goredis.StringCmd{}
-	for i := 0; i < mapLen; i++ {
-		if curRes := <-resCh; curRes.Err != nil {
-			if NewRedisHelper().IsMovedError(curRes.Err) {
-				this.initClustInfo(this.ClusterClient.Context())
-				if triedTimes < 3 {
-					triedTimes += 1
-					goto TryAgain
-				}
-			}
 
-			sCms.SetErr(curRes.Err)
-			return sCms
+	var vals = make([]interface{}, 0, len(keys))
+	for _, one := range batchRes.Results {
+		if one.Err != nil {
+			vals = append(vals, one.Err)
 		} else {
-			for key, curCmd := range curRes.Val {
-				resultMap[key] = curCmd
-			}
+			vals = append(vals, one.Val)
 		}
 	}
 
-	for _, curKey := range keys {
-		vals = append(vals, resultMap[curKey].Val())
-	}
-
 	sCms.SetVal(vals)
+	if err := batchRes.Err(); err != nil {
+		sCms.SetErr(err)
+	}
 
 	return sCms
 }
+
+// MGetWithResult records per key its value, or nil when it is missing.
+func (this *RedisCluster) MGetWithResult(ctx context.Context, keys ...string) *BatchResult {
+	// one MGET per slot.
+	return this.runBatch(ctx, keys, &batchSpec{
+		isWrite: false,
+		queue: queuePerSlot(func(ctx context.Context, pipe goredis.Pipeliner, keys []string) goredis.Cmder {
+			return pipe.MGet(ctx, keys...)
+		}),
+		parse: func(call *batchCall) []interface{} {
+			return call.Cmd.(*goredis.SliceCmd).Val()
+		},
+	})
+}
@@ -9,6 +9,7 @@ package redis
 import (
 	"errors"
 	"fmt"
+	"net"
 	"strconv"
 	"strings"
 	//goredis "github.com/go-redis/redis/v8"
@@ -20,66 +21,142 @@ type slotArea struct {
 }
 
 type redisNode struct {
-	Id        string
-	Ip        string
-	Port      string
-	Role      string // support: master or slave.
-	MasterId  string // for slave role.
-	SlotAreas []*slotArea
-	SlotName  string
+	Id          string
+	Ip          string
+	Port        string
+	Role        string // support: master or slave.
+	MasterId    string // for slave role.
+	SlotAreas   []*slotArea
+	SlotName    string
+	Flags       []string
+	PingSent    int64 // unix milliseconds, 0 when no ping is pending.
+	PongRecv    int64 // unix milliseconds.
+	ConfigEpoch uint64
+	LinkState   string // support: connected or disconnected.
+	Hostname    string
+	TlsPort     string
+
+	// the slots in migration, slot => the target node id.
+	MigratingSlots map[uint16]string
+	// the slots in migration, slot => the source node id.
+	ImportingSlots map[uint16]string
 }
 
 const (
 	ROLE_MASTER = "master"
 	ROLE_SLAVE  = "slave"
 
+	FLAG_MYSELF     = "myself"
+	FLAG_MASTER     = "master"
+	FLAG_SLAVE      = "slave"
+	FLAG_PFAIL      = "fail?"
+	FLAG_FAIL       = "fail"
+	FLAG_HANDSHAKE  = "handshake"
+	FLAG_NOADDR     = "noaddr"
+	FLAG_NOFAILOVER = "nofailover"
+
+	LINK_CONNECTED = "connected"
+
 	FIXED_SLOT_KEY = "{%s}:%s"
+
+	// the columns before the slots: id addr flags master ping-sent pong-recv config-epoch link-state.
+	SLOTS_COLUMN_OFFSET = 8
 )
 
 // for examples:
 // 25837095b1df96c37ffa96493e4bf2e693630be7 172.29.16.7:6379@1122 master - 0 1663066583000 1 connected 8192-11406 14138-16383
 // 7bc86a205acc548ffe415dc6649f636a273d655f 172.29.18.7:6379@1122 master - 0 1663066583895 2 connected 5462-8191 11407-14137
 // 8f3428825dcddfd603ad07bb6219fc756efc7102 172.29.19.4:6379@1122 myself,master - 0 1663066579000 0 connected 0-5461
+// e7d1eecce10fd6bb5eb35b9f99a514335d9ba9ca 172.29.19.5:6379@1122 slave,fail? 8f3428825dcddfd603ad07bb6219fc756efc7102 1663066579000 1663066578000 0 disconnected
 func NewRedisNode(info string) (*redisNode, error) {
 	newNode := &redisNode{}
 	err := newNode.ParseAndSet(info)
 	return newNode, err
 }
 
+// ParseAndSet parses one line of CLUSTER NODES, a node which is neither a
+// master nor a slave, like a node in handshake, is left with an empty Role.
 func (this *redisNode) ParseAndSet(info string) error {
-	comps := strings.Split(info, " - ")
-	compsLen := len(comps)
-	if compsLen == 2 {
-		return this.parseAndSetMaster(comps[0], comps[1])
-	}
-	return this.parseAndSetSlave(info)
-}
-
-func (this *redisNode) parseAndSetMaster(frontInfo, lastInfo string) error {
-	// to parse the frontInfo.
-	comps := strings.Split(frontInfo, " ")
-	compsLen := len(comps)
-	if compsLen < 3 || strings.Index(comps[2], ROLE_MASTER) < 0 {
+	comps := strings.Fields(info)
+	if len(comps) < SLOTS_COLUMN_OFFSET {
 		return errors.New("info error")
 	}
 
 	this.Id = comps[0]
-	this.Ip, this.Port = this.getAddr(comps[1])
-	this.Role = ROLE_MASTER
+	this.parseAddrColumn(comps[1])
+	this.Flags = strings.Split(comps[2], ",")
+	if comps[3] != "-" {
+		this.MasterId = comps[3]
+	}
+	this.PingSent, _ = strconv.ParseInt(comps[4], 10, 64)
+	this.PongRecv, _ = strconv.ParseInt(comps[5], 10, 64)
+	this.ConfigEpoch, _ = strconv.ParseUint(comps[6], 10, 64)
+	this.LinkState = comps[7]
+
+	switch {
+	case this.HasFlag(FLAG_MASTER):
+		this.Role = ROLE_MASTER
+		this.parseSlots(comps[SLOTS_COLUMN_OFFSET:])
+	case this.HasFlag(FLAG_SLAVE):
+		this.Role = ROLE_SLAVE
+	}
+
+	return nil
+}
 
-	// to parse the lastInfo.
-	comps = strings.Split(lastInfo, " ")
+// for examples:
+// 0-5460 5461 [5462->-e7d1eecce10fd6bb5eb35b9f99a514335d9ba9ca] [5463-<-292f8b365bb7edb5e285caf0b7e6ddc7265d2f4f]
+func (this *redisNode) parseSlots(comps []string) {
 	for _, one := range comps {
-		if strings.Index(one, "-") > 0 {
-			startSlot, endSlot := this.getSlots(one)
-			this.SlotAreas = append(this.SlotAreas, &slotArea{startSlot, endSlot})
-			if this.SlotName == "" && endSlot > 0 {
-				this.SlotName = NewCRC16().GetHashBySlotArea("n", startSlot, endSlot)
+		switch {
+		case strings.HasPrefix(one, "["):
+			this.parseSlotMigration(one)
+		case strings.Index(one, "-") > 0:
+			this.addSlotArea(this.getSlots(one))
+		default:
+			if slot, err := strconv.ParseUint(one, 10, 16); err == nil && slot < kClusterSlots {
+				this.SlotAreas = append(this.SlotAreas, &slotArea{uint16(slot), uint16(slot)})
 			}
 		}
 	}
+}
 
-	return nil
+func (this *redisNode) addSlotArea(startSlot, endSlot uint16) {
+	this.SlotAreas = append(this.SlotAreas, &slotArea{startSlot, endSlot})
+	if this.SlotName == "" && endSlot > startSlot {
+		this.SlotName = NewCRC16().GetHashBySlotArea("n", startSlot, endSlot)
+	}
+}
+
+// [5462->-nodeid] is migrating to the node, [5463-<-nodeid] is importing from the node.
+func (this *redisNode) parseSlotMigration(info string) {
+	info = strings.TrimSuffix(strings.TrimPrefix(info, "["), "]")
+
+	if comps := strings.SplitN(info, "->-", 2); len(comps) == 2 {
+		if slot, err := strconv.ParseUint(comps[0], 10, 16); err == nil && slot < kClusterSlots {
+			if this.MigratingSlots == nil {
+				this.MigratingSlots = map[uint16]string{}
+			}
+			this.MigratingSlots[uint16(slot)] = comps[1]
+		}
+	} else if comps := strings.SplitN(info, "-<-", 2); len(comps) == 2 {
+		if slot, err := strconv.ParseUint(comps[0], 10, 16); err == nil && slot < kClusterSlots {
+			if this.ImportingSlots == nil {
+				this.ImportingSlots = map[uint16]string{}
+			}
+			this.ImportingSlots[uint16(slot)] = comps[1]
+		}
+	}
+}
+
+func (this *redisNode) IsMigrating(slot uint16) bool {
+	_, isExists := this.MigratingSlots[slot]
+	return isExists
+}
+
+func (this *redisNode) IsImporting(slot uint16) bool {
+	_, isExists := this.ImportingSlots[slot]
+	return isExists
 }
 
 func (this *redisNode) getSlots(info string) (uint16, uint16) {
@@ -93,48 +170,78 @@ func (this *redisNode) getSlots(info string) (uint16, uint16) {
 	return uint16(startSlot), uint16(endSlot)
 }
 
+// for examples:
+// 172.29.16.7:6379@1122
+// [::1]:6379@16379 or ::1:6379@16379
+// 172.29.16.7:6379@1122,redis-0.example.com
+// 172.29.16.7:6379@1122,redis-0.example.com,tls-port=6380,shard-id=c2e9...
+func (this *redisNode) parseAddrColumn(info string) {
+	comps := strings.Split(info, ",")
+	this.Ip, this.Port = this.getAddr(comps[0])
+
+	for i, one := range comps[1:] {
+		if kv := strings.SplitN(one, "=", 2); len(kv) == 2 {
+			switch kv[0] {
+			case "hostname":
+				this.Hostname = kv[1]
+			case "tls-port":
+				this.TlsPort = kv[1]
+			}
+		} else if i == 0 {
+			this.Hostname = one
+		}
+	}
+}
+
+// to split the host and port, the port is after the last colon so the IPv6
+// addresses are supported with or without the brackets.
 func (this *redisNode) getAddr(info string) (string, string) {
-	comps := strings.Split(info, "@")
-	if len(comps) == 2 {
-		info = comps[0]
+	if idx := strings.Index(info, "@"); idx >= 0 {
+		info = info[:idx]
 	}
-	comps = strings.Split(info, ":")
-	if len(comps) != 2 {
+	if idx := strings.Index(info, ","); idx >= 0 {
+		info = info[:idx]
+	}
+
+	idx := strings.LastIndex(info, ":")
+	if idx <= 0 || idx == len(info)-1 {
 		return "", ""
 	}
 
-	return comps[0], comps[1]
+	host := strings.TrimSuffix(strings.TrimPrefix(info[:idx], "["), "]")
+	return host, info[idx+1:]
 }
 
-func (this *redisNode) parseAndSetSlave(info string) error {
-	comps := strings.Split(info, "slave")
-	compsLen := len(comps)
-	if compsLen != 2 {
-		return errors.New("not slave node")
+func (this *redisNode) HasFlag(flag string) bool {
+	for _, one := range this.Flags {
+		if one == flag {
+			return true
+		}
 	}
+	return false
+}
 
-	frontInfo := comps[0]
-	lastInfo := comps[1]
+func (this *redisNode) IsFailed() bool {
+	return this.HasFlag(FLAG_FAIL)
+}
 
-	// to parse the frontInfo.
-	comps = strings.Split(frontInfo, " ")
-	if len(comps) < 2 {
-		return errors.New("slave info error")
-	}
-	this.Id = comps[0]
-	this.Ip, this.Port = this.getAddr(comps[1])
-	this.Role = ROLE_SLAVE
+// IsAvailable tells whether the node can serve the requests.
+func (this *redisNode) IsAvailable() bool {
+	return !this.HasFlag(FLAG_FAIL) && !this.HasFlag(FLAG_PFAIL) &&
+		!this.HasFlag(FLAG_HANDSHAKE) && !this.HasFlag(FLAG_NOADDR)
+}
 
-	// to parse the lastInfo.
-	comps = strings.Split(lastInfo, " ")
-	for _, one := range comps {
-		if one != "" {
-			this.MasterId = one
-			break
-		}
-	}
+func (this *redisNode) Addr() string {
+	return net.JoinHostPort(this.Ip, this.Port)
+}
 
-	return nil
+// DialAddr returns the announced hostname with the port when it is preferred
+// and known, otherwise the ip with the port.
+func (this *redisNode) DialAddr(preferHostname bool) string {
+	if preferHostname && this.Hostname != "" {
+		return net.JoinHostPort(this.Hostname, this.Port)
+	}
+	return this.Addr()
 }
 
 func (this *redisNode) RebuildKey(key string) string {
@@ -0,0 +1,98 @@
+// Copyright (C) 2022
+// Author FrankXu <frankxury@gmail.com>
+// Build on 2026/10/18
+
+// The redis node test.
+
+package redis
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestParseSlots(t *testing.T) {
+	assert := assert.New(t)
+	node, err := NewRedisNode("8f3428825dcddfd603ad07bb6219fc756efc7102 172.29.19.4:6379@1122 myself,master - 0 1663066579000 1 connected 0-5459 5460 [5461->-25837095b1df96c37ffa96493e4bf2e693630be7] [5462-<-7bc86a205acc548ffe415dc6649f636a273d655f]")
+	assert.Nil(err)
+
+	assert.Equal([]*slotArea{{0, 5459}, {5460, 5460}}, node.SlotAreas)
+	assert.Equal(map[uint16]string{5461: "25837095b1df96c37ffa96493e4bf2e693630be7"}, node.MigratingSlots)
+	assert.Equal(map[uint16]string{5462: "7bc86a205acc548ffe415dc6649f636a273d655f"}, node.ImportingSlots)
+	assert.True(node.IsMigrating(5461))
+	assert.True(node.IsImporting(5462))
+	assert.False(node.IsMigrating(5460))
+}
+
+func TestParseNodeColumns(t *testing.T) {
+	testCases := []struct {
+		Info      string
+		Role      string
+		MasterId  string
+		Available bool
+		Failed    bool
+	}{
+		{"aaaa 10.0.0.1:6379@16379 myself,master - 0 1663066579000 3 connected 0-16383", ROLE_MASTER, "", true, false},
+		{"aaaa 10.0.0.1:6379@16379 master,fail - 1663066579000 1663066578000 3 disconnected", ROLE_MASTER, "", false, true},
+		{"bbbb 10.0.0.2:6379@16379 slave,fail? aaaa 1663066579000 1663066578000 3 connected", ROLE_SLAVE, "aaaa", false, false},
+		{"bbbb 10.0.0.2:6379@16379 myself,slave,nofailover aaaa 0 1663066578000 3 connected", ROLE_SLAVE, "aaaa", true, false},
+		{"cccc :0@0 handshake,noaddr - 0 0 0 disconnected", "", "", false, false},
+	}
+
+	assert := assert.New(t)
+	for _, test := range testCases {
+		node, err := NewRedisNode(test.Info)
+		assert.Nil(err, test.Info)
+		assert.Equal(test.Role, node.Role, test.Info)
+		assert.Equal(test.MasterId, node.MasterId, test.Info)
+		assert.Equal(test.Available, node.IsAvailable(), test.Info)
+		assert.Equal(test.Failed, node.IsFailed(), test.Info)
+	}
+
+	node, _ := NewRedisNode("aaaa 10.0.0.1:6379@16379 slave bbbb 1663066579001 1663066578002 7 disconnected")
+	assert.Equal(int64(1663066579001), node.PingSent)
+	assert.Equal(int64(1663066578002), node.PongRecv)
+	assert.Equal(uint64(7), node.ConfigEpoch)
+	assert.Equal("disconnected", node.LinkState)
+	assert.True(node.HasFlag(FLAG_SLAVE))
+
+	_, err := NewRedisNode("aaaa 10.0.0.1:6379@16379 master -")
+	assert.NotNil(err)
+}
+
+func TestParseAddrColumn(t *testing.T) {
+	testCases := []struct {
+		Addr     string
+		Ip       string
+		Port     string
+		Hostname string
+		TlsPort  string
+	}{
+		{"172.29.16.7:6379@1122", "172.29.16.7", "6379", "", ""},
+		{"172.29.16.7:6379", "172.29.16.7", "6379", "", ""},
+		{"[::1]:6379@16379", "::1", "6379", "", ""},
+		{"2001:db8::7:6379@16379", "2001:db8::7", "6379", "", ""},
+		{"172.29.16.7:6379@1122,redis-0.example.com", "172.29.16.7", "6379", "redis-0.example.com", ""},
+		{"[fe80::1]:6379@16379,redis-1,tls-port=6380,shard-id=c2e9", "fe80::1", "6379", "redis-1", "6380"},
+		{"172.29.16.7:6379@1122,,tls-port=0,shard-id=c2e9", "172.29.16.7", "6379", "", "0"},
+		{":0@0", "", "", "", ""},
+	}
+
+	assert := assert.New(t)
+	for _, test := range testCases {
+		node, err := NewRedisNode("aaaa " + test.Addr + " master - 0 0 1 connected")
+		assert.Nil(err)
+		assert.Equal(test.Ip, node.Ip, test.Addr)
+		assert.Equal(test.Port, node.Port, test.Addr)
+		assert.Equal(test.Hostname, node.Hostname, test.Addr)
+		assert.Equal(test.TlsPort, node.TlsPort, test.Addr)
+	}
+
+	node, _ := NewRedisNode("aaaa [::1]:6379@16379,redis-0 master - 0 0 1 connected")
+	assert.Equal("[::1]:6379", node.Addr())
+	assert.Equal("[::1]:6379", node.DialAddr(false))
+	assert.Equal("redis-0:6379", node.DialAddr(true))
+
+	node, _ = NewRedisNode("aaaa 10.0.0.1:6379@16379 master - 0 0 1 connected")
+	assert.Equal("10.0.0.1:6379", node.DialAddr(true))
+}
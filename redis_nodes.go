@@ -9,6 +9,7 @@ package redis
 import (
 	"strings"
 	"sync"
+	"sync/atomic"
 )
 
 type redisGroup struct {
@@ -16,72 +17,164 @@ type redisGroup struct {
 	slaves []*redisNode
 }
 
-type redisNodes struct {
+// slotTable maps every hash slot to the group serving it.
+type slotTable [kClusterSlots]*redisGroup
+
+// nodesSnapshot is an immutable view of the topology, it is never modified
+// once it has been published, a refresh swaps in a new one.
+type nodesSnapshot struct {
 	groupMap map[string]*redisGroup
-	lock     sync.Mutex
+	slots    *slotTable
 }
 
-var (
-	oneRedisNodes *redisNodes
-)
+type redisNodes struct {
+	snapshot atomic.Value // *nodesSnapshot
+	loaded   atomic.Value // *nodesSnapshot, the last one set, without the patches.
+	lock     sync.Mutex
+}
 
 // 25837095b1df96c37ffa96493e4bf2e693630be7 172.29.16.7:6379@1122 master - 0 1663066583000 1 connected 8192-11406 14138-16383\n7bc86a205acc548ffe415dc6649f636a273d655f 172.29.18.7:6379@1122 master - 0 1663066583895 2 connected 5462-8191 11407-14137\n8f3428825dcddfd603ad07bb6219fc756efc7102 172.29.19.4:6379@1122 myself,master - 0 1663066579000 0 connected 0-5461\n
 func NewRedisNodes(info string) (*redisNodes, error) {
-	if oneRedisNodes == nil {
-		oneRedisNodes = &redisNodes{groupMap: map[string]*redisGroup{}}
-	}
-	err := oneRedisNodes.ParseAndSet(info)
-	return oneRedisNodes, err
+	newNodes := &redisNodes{}
+	err := newNodes.ParseAndSet(info)
+	return newNodes, err
 }
 
 func (this *redisNodes) ParseAndSet(info string) error {
 	comps := strings.Split(info, "\n")
-	newMap := map[string]*redisGroup{}
+	nodes := []*redisNode{}
 	for _, nodeInfo := range comps {
 		if nodeInfo == "" {
 			continue
 		}
 		if node, err := NewRedisNode(nodeInfo); err == nil {
-			switch node.Role {
-			case ROLE_MASTER:
-				if _, isExists := newMap[node.Id]; !isExists {
-					newMap[node.Id] = &redisGroup{slaves: []*redisNode{}}
-				}
-				newMap[node.Id].master = node
-			case ROLE_SLAVE:
-				if _, isExists := newMap[node.MasterId]; !isExists {
-					newMap[node.MasterId] = &redisGroup{slaves: []*redisNode{}}
-				}
-				newMap[node.MasterId].slaves = append(newMap[node.MasterId].slaves, node)
-			}
+			nodes = append(nodes, node)
 		} else {
 			return err
 		}
 	}
 
+	this.SetNodes(nodes)
+
+	return nil
+}
+
+// SetNodes groups the nodes by master and publishes a new snapshot.
+func (this *redisNodes) SetNodes(nodes []*redisNode) {
+	newMap := map[string]*redisGroup{}
+	for _, node := range nodes {
+		switch node.Role {
+		case ROLE_MASTER:
+			if _, isExists := newMap[node.Id]; !isExists {
+				newMap[node.Id] = &redisGroup{slaves: []*redisNode{}}
+			}
+			newMap[node.Id].master = node
+		case ROLE_SLAVE:
+			if node.MasterId == "" {
+				continue
+			}
+			if _, isExists := newMap[node.MasterId]; !isExists {
+				newMap[node.MasterId] = &redisGroup{slaves: []*redisNode{}}
+			}
+			newMap[node.MasterId].slaves = append(newMap[node.MasterId].slaves, node)
+		}
+	}
+
+	snap := newNodesSnapshot(newMap)
 	this.lock.Lock()
-	this.groupMap = newMap
+	this.snapshot.Store(snap)
+	this.loaded.Store(snap)
 	this.lock.Unlock()
+}
+
+// to compile the groups into the slot table.
+func newNodesSnapshot(groupMap map[string]*redisGroup) *nodesSnapshot {
+	slots := &slotTable{}
+	for _, group := range groupMap {
+		if group.master == nil {
+			continue
+		}
+		for _, area := range group.master.SlotAreas {
+			for slot := int(area.StartSlot); slot <= int(area.EndSlot) && slot < kClusterSlots; slot++ {
+				slots[slot] = group
+			}
+		}
+	}
 
+	return &nodesSnapshot{groupMap: groupMap, slots: slots}
+}
+
+// Snapshot returns the current topology, or nil before the first parse.
+func (this *redisNodes) Snapshot() *nodesSnapshot {
+	if snap, isOk := this.snapshot.Load().(*nodesSnapshot); isOk {
+		return snap
+	}
 	return nil
 }
 
+// LoadedSnapshot returns the topology last loaded from the cluster, the slots
+// patched by the redirections since are not in it.
+func (this *redisNodes) LoadedSnapshot() *nodesSnapshot {
+	if snap, isOk := this.loaded.Load().(*nodesSnapshot); isOk {
+		return snap
+	}
+	return nil
+}
+
+func (this *nodesSnapshot) FindNodeByCRC16Val(crc16Val uint16) (*redisGroup, bool) {
+	if this == nil || crc16Val >= kClusterSlots {
+		return nil, false
+	}
+	hitNode := this.slots[crc16Val]
+	return hitNode, hitNode != nil
+}
+
 func (this *redisNodes) FindNodeByCRC16Val(crc16Val uint16) (*redisGroup, bool) {
-	var hitNode *redisGroup
-	isFound := false
-	for _, node := range this.groupMap {
-		for _, slotArea := range node.master.SlotAreas {
-			if crc16Val >= slotArea.StartSlot && crc16Val <= slotArea.EndSlot {
-				isFound = true
-				hitNode = node
-				break
-			}
+	return this.Snapshot().FindNodeByCRC16Val(crc16Val)
+}
+
+// PatchSlots moves the slots to the masters listening on the addrs, it is
+// used to apply the MOVED redirections without reloading the topology. An
+// unknown addr gets a temporary group until the next full refresh, the
+// refresh diffs with the LoadedSnapshot so the patches are never published.
+func (this *redisNodes) PatchSlots(slotAddrs map[uint16]string) {
+	if len(slotAddrs) == 0 {
+		return
+	}
+
+	this.lock.Lock()
+	defer this.lock.Unlock()
+
+	snap := this.Snapshot()
+	if snap == nil {
+		return
+	}
+
+	addrMap := map[string]*redisGroup{}
+	newMap := make(map[string]*redisGroup, len(snap.groupMap))
+	for id, group := range snap.groupMap {
+		newMap[id] = group
+		if group.master != nil {
+			addrMap[group.master.Addr()] = group
+			addrMap[group.master.DialAddr(true)] = group
 		}
+	}
 
-		if isFound {
-			break
+	newSlots := *snap.slots
+	for slot, addr := range slotAddrs {
+		if slot >= kClusterSlots {
+			continue
+		}
+		group, isExists := addrMap[addr]
+		if !isExists {
+			master := &redisNode{Id: addr, Role: ROLE_MASTER}
+			master.Ip, master.Port = master.getAddr(addr)
+			group = &redisGroup{master: master, slaves: []*redisNode{}}
+			addrMap[addr] = group
+			newMap[addr] = group
 		}
+		newSlots[slot] = group
 	}
 
-	return hitNode, isFound
+	this.snapshot.Store(&nodesSnapshot{groupMap: newMap, slots: &newSlots})
 }
@@ -0,0 +1,97 @@
+// Copyright (C) 2022
+// Author FrankXu <frankxury@gmail.com>
+// Build on 2022/07/12
+
+// The redis nodes test.
+
+package redis
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+const testClusterNodes = "25837095b1df96c37ffa96493e4bf2e693630be7 172.29.16.7:6379@1122 master - 0 1663066583000 1 connected 8192-11406 14138-16383\n" +
+	"7bc86a205acc548ffe415dc6649f636a273d655f 172.29.18.7:6379@1122 master - 0 1663066583895 2 connected 5462-8191 11407-14137\n" +
+	"8f3428825dcddfd603ad07bb6219fc756efc7102 172.29.19.4:6379@1122 myself,master - 0 1663066579000 0 connected 0-5461\n"
+
+func TestFindNodeByCRC16Val(t *testing.T) {
+	testCases := []struct {
+		Slot uint16
+		Id   string
+	}{
+		{0, "8f3428825dcddfd603ad07bb6219fc756efc7102"},
+		{5461, "8f3428825dcddfd603ad07bb6219fc756efc7102"},
+		{5462, "7bc86a205acc548ffe415dc6649f636a273d655f"},
+		{11406, "25837095b1df96c37ffa96493e4bf2e693630be7"},
+		{11407, "7bc86a205acc548ffe415dc6649f636a273d655f"},
+		{16383, "25837095b1df96c37ffa96493e4bf2e693630be7"},
+	}
+
+	assert := assert.New(t)
+	nodes, err := NewRedisNodes(testClusterNodes)
+	assert.Nil(err)
+
+	for _, test := range testCases {
+		group, isFound := nodes.FindNodeByCRC16Val(test.Slot)
+		assert.True(isFound, "slot %d not found", test.Slot)
+		assert.Equal(test.Id, group.master.Id, "slot %d failed.", test.Slot)
+	}
+
+	_, isFound := nodes.FindNodeByCRC16Val(kClusterSlots)
+	assert.False(isFound)
+}
+
+func TestRedisNodesAreIndependent(t *testing.T) {
+	assert := assert.New(t)
+	first, err := NewRedisNodes(testClusterNodes)
+	assert.Nil(err)
+	second, err := NewRedisNodes("8f3428825dcddfd603ad07bb6219fc756efc7102 172.29.19.4:6379@1122 myself,master - 0 1663066579000 0 connected 0-16383\n")
+	assert.Nil(err)
+
+	group, _ := first.FindNodeByCRC16Val(16383)
+	assert.Equal("25837095b1df96c37ffa96493e4bf2e693630be7", group.master.Id)
+	group, _ = second.FindNodeByCRC16Val(16383)
+	assert.Equal("8f3428825dcddfd603ad07bb6219fc756efc7102", group.master.Id)
+}
+
+func TestPatchSlots(t *testing.T) {
+	assert := assert.New(t)
+	nodes, err := NewRedisNodes(testClusterNodes)
+	assert.Nil(err)
+	before := nodes.Snapshot()
+
+	nodes.PatchSlots(map[uint16]string{
+		0:    "172.29.16.7:6379",
+		5461: "172.29.20.1:6379",
+	})
+
+	group, _ := nodes.FindNodeByCRC16Val(0)
+	assert.Equal("25837095b1df96c37ffa96493e4bf2e693630be7", group.master.Id)
+	group, _ = nodes.FindNodeByCRC16Val(5461)
+	assert.Equal("172.29.20.1:6379", group.master.Addr())
+	group, _ = nodes.FindNodeByCRC16Val(1)
+	assert.Equal("8f3428825dcddfd603ad07bb6219fc756efc7102", group.master.Id)
+
+	// the published snapshot is never modified.
+	group, _ = before.FindNodeByCRC16Val(0)
+	assert.Equal("8f3428825dcddfd603ad07bb6219fc756efc7102", group.master.Id)
+
+	// the loaded snapshot has none of the patches.
+	assert.True(before == nodes.LoadedSnapshot())
+	_, isExists := nodes.LoadedSnapshot().groupMap["172.29.20.1:6379"]
+	assert.False(isExists)
+}
+
+func TestFindNodeBySingleSlot(t *testing.T) {
+	assert := assert.New(t)
+	nodes, err := NewRedisNodes("aaaa 10.0.0.1:6379@16379 master - 0 0 1 connected 0-5459 5460 [5461->-bbbb]\n" +
+		"bbbb 10.0.0.2:6379@16379 master - 0 0 2 connected 5461-16383 [5461-<-aaaa]\n")
+	assert.Nil(err)
+
+	group, isFound := nodes.FindNodeByCRC16Val(5460)
+	assert.True(isFound)
+	assert.Equal("aaaa", group.master.Id)
+	group, _ = nodes.FindNodeByCRC16Val(5461)
+	assert.Equal("bbbb", group.master.Id)
+}
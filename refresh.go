@@ -0,0 +1,97 @@
+// Copyright (C) 2022
+// Author FrankXu <frankxury@gmail.com>
+// Build on 2026/10/18
+
+// The topology refresh of the redis cluster.
+
+package redis
+
+import (
+	"context"
+	"log"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	MIN_REFRESH_INTERVAL = time.Second
+)
+
+// scheduleRefresh reloads the topology in the background. The refreshes are
+// rate limited by MIN_REFRESH_INTERVAL, and the calls made while one refresh
+// is pending are merged into it.
+func (this *RedisCluster) scheduleRefresh() {
+	if !atomic.CompareAndSwapInt32(&this.isRefreshPending, 0, 1) {
+		return
+	}
+
+	go func() {
+		defer atomic.StoreInt32(&this.isRefreshPending, 0)
+
+		lastRefreshAt := time.Unix(0, atomic.LoadInt64(&this.lastRefreshAt))
+		if wait := time.Until(lastRefreshAt.Add(MIN_REFRESH_INTERVAL)); wait > 0 {
+			timer := time.NewTimer(wait)
+			defer timer.Stop()
+
+			select {
+			case <-timer.C:
+			case <-this.closeCh:
+				return
+			}
+		}
+
+		if err := this.initClustInfo(context.Background()); err != nil {
+			log.Printf("Failed to refresh the cluster topology: %s", err.Error())
+		}
+	}()
+}
+
+// RefreshTopology reloads the topology at once and publishes the changes.
+func (this *RedisCluster) RefreshTopology(ctx context.Context) error {
+	return this.initClustInfo(ctx)
+}
+
+// SubscribeTopologyEvents returns a channel receiving the topology changes and
+// the function to unsubscribe. The events are dropped when the channel is full,
+// so the refresh is never blocked by a slow listener.
+func (this *RedisCluster) SubscribeTopologyEvents(size int) (<-chan *TopologyEvent, func()) {
+	return this.notifier.subscribe(size)
+}
+
+// to publish the events, it is called with the refreshLock held so the order
+// is kept, the callback must not refresh the topology by itself.
+func (this *RedisCluster) publishEvents(events []*TopologyEvent) {
+	if len(events) == 0 {
+		return
+	}
+
+	if this.extOpts.OnTopologyEvent != nil {
+		for _, event := range events {
+			this.extOpts.OnTopologyEvent(event)
+		}
+	}
+	this.notifier.publish(events)
+}
+
+// to refresh the topology by the RefreshInterval until the cluster is closed.
+func (this *RedisCluster) startRefresher() {
+	if this.extOpts.RefreshInterval <= 0 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(this.extOpts.RefreshInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				if err := this.initClustInfo(context.Background()); err != nil {
+					log.Printf("Failed to refresh the cluster topology: %s", err.Error())
+				}
+			case <-this.closeCh:
+				return
+			}
+		}
+	}()
+}
@@ -0,0 +1,183 @@
+// Copyright (C) 2022
+// Author FrankXu <frankxury@gmail.com>
+// Build on 2026/10/18
+
+// The Rename and Copy across the slots, by DUMP and RESTORE.
+
+package redis
+
+import (
+	"context"
+	"errors"
+	goredis "github.com/go-redis/redis/v8"
+	"strings"
+	"time"
+)
+
+const (
+	// to delete the source only when its dump is unchanged, -1 when changed.
+	// KEYS[1]: the source, ARGV[1]: the dump.
+	LUA_DEL_IF_DUMP_EQUAL = `
+if redis.call('dump', KEYS[1]) == ARGV[1] then
+	return redis.call('del', KEYS[1])
+end
+return -1`
+)
+
+var (
+	ErrNoSuchKey   = errors.New("ERR no such key")
+	ErrKeyModified = errors.New("The source key was modified during the move.")
+)
+
+// the serialized value of a key with its ttl, 0 for no ttl.
+type keyDump struct {
+	val string
+	ttl time.Duration
+}
+
+// Rename renames the key, the value is moved by DUMP and RESTORE when the
+// keys are in different slots, with the ttl.
+func (this *RedisCluster) Rename(ctx context.Context, key, newkey string) *goredis.StatusCmd {
+	if this.isSameSlot(key, newkey) {
+		return this.ClusterClient.Rename(ctx, key, newkey)
+	}
+
+	result := goredis.NewStatusCmd(ctx, "rename", key, newkey)
+	if _, err := this.moveKey(ctx, key, newkey, true, true); err != nil {
+		result.SetErr(err)
+		return result
+	}
+
+	result.SetVal("OK")
+	return result
+}
+
+// RenameNX renames the key only when the newkey does not exist, like Rename.
+func (this *RedisCluster) RenameNX(ctx context.Context, key, newkey string) *goredis.BoolCmd {
+	if this.isSameSlot(key, newkey) {
+		return this.ClusterClient.RenameNX(ctx, key, newkey)
+	}
+
+	result := goredis.NewBoolCmd(ctx, "renamenx", key, newkey)
+	isMoved, err := this.moveKey(ctx, key, newkey, false, true)
+	if err != nil {
+		result.SetErr(err)
+		return result
+	}
+
+	result.SetVal(isMoved)
+	return result
+}
+
+// Copy copies the value of the source to the dest, by DUMP and RESTORE when
+// the keys are in different slots. 0 is replied when the source is missing,
+// or the dest exists without replace.
+func (this *RedisCluster) Copy(ctx context.Context, sourceKey, destKey string, db int, replace bool) *goredis.IntCmd {
+	if db != 0 || this.isSameSlot(sourceKey, destKey) {
+		return this.ClusterClient.Copy(ctx, sourceKey, destKey, db, replace)
+	}
+
+	result := goredis.NewIntCmd(ctx, "copy", sourceKey, destKey)
+	isCopied, err := this.moveKey(ctx, sourceKey, destKey, replace, false)
+	if err == ErrNoSuchKey {
+		err = nil
+	}
+	if err != nil {
+		result.SetErr(err)
+		return result
+	}
+
+	if isCopied {
+		result.SetVal(1)
+	}
+	return result
+}
+
+// moveKey restores the dump of the source at the dest, and deletes the source
+// when asked. False is returned when the dest exists without replace. With
+// GuardKeyMoves, the source changed since the dump is kept and the dest is
+// rolled back.
+func (this *RedisCluster) moveKey(ctx context.Context, src, dest string, isReplace bool, isDelete bool) (bool, error) {
+	srcDump, err := this.dumpKey(ctx, src)
+	if err != nil {
+		return false, err
+	} else if srcDump == nil {
+		return false, ErrNoSuchKey
+	}
+
+	isGuarded := isDelete && this.extOpts.GuardKeyMoves
+	var destDump *keyDump
+	if isGuarded && isReplace {
+		if destDump, err = this.dumpKey(ctx, dest); err != nil {
+			return false, err
+		}
+	}
+
+	if err := this.restoreKey(ctx, dest, srcDump, isReplace); err != nil {
+		if strings.HasPrefix(err.Error(), "BUSYKEY") {
+			return false, nil
+		}
+		return false, err
+	}
+
+	if !isDelete {
+		return true, nil
+	} else if !isGuarded {
+		return true, this.ClusterClient.Del(ctx, src).Err()
+	}
+
+	deleted, err := this.ClusterClient.Eval(ctx, LUA_DEL_IF_DUMP_EQUAL, []string{src}, srcDump.val).Int64()
+	if err != nil {
+		return true, err
+	} else if deleted >= 0 {
+		return true, nil
+	}
+
+	// the source was changed, so the dest is put back.
+	if destDump != nil {
+		err = this.restoreKey(ctx, dest, destDump, true)
+	} else {
+		err = this.ClusterClient.Del(ctx, dest).Err()
+	}
+	if err != nil {
+		return true, err
+	}
+	return false, ErrKeyModified
+}
+
+// to dump the key with its ttl in one transaction, nil for a missing key.
+func (this *RedisCluster) dumpKey(ctx context.Context, key string) (*keyDump, error) {
+	var dumpCmd *goredis.StringCmd
+	var ttlCmd *goredis.DurationCmd
+	_, err := this.ClusterClient.TxPipelined(ctx, func(pipe goredis.Pipeliner) error {
+		dumpCmd = pipe.Dump(ctx, key)
+		ttlCmd = pipe.PTTL(ctx, key)
+		return nil
+	})
+
+	if dumpCmd != nil && dumpCmd.Err() == goredis.Nil {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	return newKeyDump(dumpCmd.Val(), ttlCmd.Val()), nil
+}
+
+// the PTTL replies a negative value for a key without ttl, and 0 for a key
+// expiring within 1ms, which is kept expiring rather than restored without ttl.
+func newKeyDump(val string, ttl time.Duration) *keyDump {
+	if ttl < 0 {
+		ttl = 0
+	} else if ttl == 0 {
+		ttl = time.Millisecond
+	}
+	return &keyDump{val: val, ttl: ttl}
+}
+
+func (this *RedisCluster) restoreKey(ctx context.Context, key string, dump *keyDump, isReplace bool) error {
+	if isReplace {
+		return this.ClusterClient.RestoreReplace(ctx, key, dump.ttl, dump.val).Err()
+	}
+	return this.ClusterClient.Restore(ctx, key, dump.ttl, dump.val).Err()
+}
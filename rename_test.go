@@ -0,0 +1,103 @@
+// Copyright (C) 2022
+// Author FrankXu <frankxury@gmail.com>
+// Build on 2026/10/18
+
+// The cross slot rename test.
+
+package redis
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestNewKeyDump(t *testing.T) {
+	assert := assert.New(t)
+
+	// no ttl is restored as 0, and the ttl of 0 is kept as 1ms.
+	assert.Equal(&keyDump{val: "dump"}, newKeyDump("dump", -1))
+	assert.Equal(&keyDump{val: "dump", ttl: time.Millisecond}, newKeyDump("dump", 0))
+	assert.Equal(&keyDump{val: "dump", ttl: 1500 * time.Millisecond}, newKeyDump("dump", 1500*time.Millisecond))
+
+	ext := (&ExtOptions{GuardKeyMoves: true}).init()
+	assert.True(ext.GuardKeyMoves)
+}
+
+func TestRenameOnFake(t *testing.T) {
+	assert := assert.New(t)
+	cluster, fake := newFakeCluster(t, nil)
+	ctx := context.Background()
+
+	// the value is moved with its ttl.
+	expireAt := time.Now().Add(time.Minute).UnixNano() / 1e6
+	fake.Set("a", &fakeEntry{Type: "hash", Hash: map[string]string{"f": "1"}, ExpireAt: expireAt})
+	assert.Nil(cluster.Rename(ctx, "a", "b").Err())
+	assert.Nil(fake.Get("a"))
+	assert.Equal(map[string]string{"f": "1"}, fake.Get("b").Hash)
+	assert.InDelta(expireAt, fake.Get("b").ExpireAt, 1000)
+	assert.Equal(ErrNoSuchKey, cluster.Rename(ctx, "a", "b").Err())
+
+	// the dest existing is not replaced by RenameNX.
+	fake.Set("a", &fakeEntry{Type: "string", Str: "1"})
+	isMoved, err := cluster.RenameNX(ctx, "a", "b").Result()
+	assert.Nil(err)
+	assert.False(isMoved)
+	assert.Equal("1", fake.Get("a").Str)
+	assert.Equal("hash", fake.Get("b").Type)
+	assert.True(cluster.RenameNX(ctx, "b", "c").Val())
+	assert.InDelta(expireAt, fake.Get("c").ExpireAt, 1000)
+
+	// the source is kept by Copy.
+	assert.Equal(int64(0), cluster.Copy(ctx, "a", "c", 0, false).Val())
+	assert.Equal(int64(1), cluster.Copy(ctx, "a", "c", 0, true).Val())
+	assert.Equal("1", fake.Get("a").Str)
+	assert.Equal("1", fake.Get("c").Str)
+	assert.Equal(int64(0), cluster.Copy(ctx, "missing", "c", 0, true).Val())
+	assert.NotContains(fake.Cmds(), "rename")
+	assert.NotContains(fake.Cmds(), "copy")
+
+	// the keys of one slot are moved by the native commands.
+	fake.Set("{u}a", &fakeEntry{Type: "string", Str: "2"})
+	assert.Nil(cluster.Rename(ctx, "{u}a", "{u}b").Err())
+	assert.Equal(int64(1), cluster.Copy(ctx, "{u}b", "{u}c", 0, false).Val())
+	assert.Equal("2", fake.Get("{u}c").Str)
+	assert.Contains(fake.Cmds(), "rename")
+	assert.Contains(fake.Cmds(), "copy")
+}
+
+func TestGuardKeyMoves(t *testing.T) {
+	assert := assert.New(t)
+	cluster, fake := newFakeCluster(t, &ExtOptions{GuardKeyMoves: true})
+	ctx := context.Background()
+
+	// the source is changed by another writer on every restore of the dest,
+	// the rollback included.
+	changed := 0
+	fake.SetHook(func(args []string) {
+		if args[0] == "restore" && args[1] == "b" {
+			changed++
+			fake.setEntry("a", &fakeEntry{Type: "string", Str: "changed-" + strconv.Itoa(changed)})
+		}
+	})
+
+	fake.Set("a", &fakeEntry{Type: "string", Str: "1"})
+	fake.Set("b", &fakeEntry{Type: "string", Str: "old"})
+	assert.Equal(ErrKeyModified, cluster.Rename(ctx, "a", "b").Err())
+	assert.Equal("changed-2", fake.Get("a").Str)
+	assert.Equal("old", fake.Get("b").Str)
+
+	// the dest missing before is deleted.
+	fake.Set("b", nil)
+	assert.Equal(ErrKeyModified, cluster.RenameNX(ctx, "a", "b").Err())
+	assert.Equal("changed-3", fake.Get("a").Str)
+	assert.Nil(fake.Get("b"))
+
+	// the source unchanged is deleted.
+	fake.SetHook(nil)
+	assert.Nil(cluster.Rename(ctx, "a", "b").Err())
+	assert.Nil(fake.Get("a"))
+	assert.Equal("changed-3", fake.Get("b").Str)
+}
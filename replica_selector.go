@@ -0,0 +1,122 @@
+// Copyright (C) 2022
+// Author FrankXu <frankxury@gmail.com>
+// Build on 2026/10/18
+
+// The replica selectors of the reads.
+
+package redis
+
+import (
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ReplicaNode is the view of a node given to the replica selectors.
+type ReplicaNode struct {
+	Id       string
+	Addr     string
+	Hostname string
+	IsMaster bool
+	Latency  time.Duration // 0 when it has never been measured.
+}
+
+// ReplicaSelector selects the node serving a read among the available nodes
+// of one shard. The master is nil when it is unavailable, and returning nil
+// falls back to the read policy.
+type ReplicaSelector interface {
+	Select(master *ReplicaNode, replicas []*ReplicaNode) *ReplicaNode
+}
+
+// ZoneAffinitySelector prefers the replicas in the local zone, then falls
+// back to the master in the local zone, the replicas in the other zones and
+// finally the master anywhere. With FallbackToMaster the master is preferred
+// to the replicas in the other zones.
+type ZoneAffinitySelector struct {
+	LocalZone string
+
+	// node id, addr or hostname => zone.
+	NodeZones map[string]string
+
+	// To discover the zone from the announced hostname when the node is not
+	// in NodeZones. The default one finds the LocalZone in the hostname labels,
+	// like "redis-0.us-east-1a.cache.internal" or "redis-0-us-east-1a".
+	ZoneFromHostname func(hostname string) string
+
+	FallbackToMaster bool
+}
+
+func NewZoneAffinitySelector(localZone string, nodeZones map[string]string) *ZoneAffinitySelector {
+	return &ZoneAffinitySelector{LocalZone: localZone, NodeZones: nodeZones}
+}
+
+func (this *ZoneAffinitySelector) Select(master *ReplicaNode, replicas []*ReplicaNode) *ReplicaNode {
+	localReplicas, otherReplicas := []*ReplicaNode{}, []*ReplicaNode{}
+	for _, one := range replicas {
+		if this.IsLocal(one) {
+			localReplicas = append(localReplicas, one)
+		} else {
+			otherReplicas = append(otherReplicas, one)
+		}
+	}
+
+	if len(localReplicas) > 0 {
+		return localReplicas[randIntn(len(localReplicas))]
+	}
+	if master != nil && (this.FallbackToMaster || this.IsLocal(master)) {
+		return master
+	}
+	if len(otherReplicas) > 0 {
+		return otherReplicas[randIntn(len(otherReplicas))]
+	}
+	return master
+}
+
+func (this *ZoneAffinitySelector) IsLocal(node *ReplicaNode) bool {
+	return this.LocalZone != "" && this.ZoneOf(node) == this.LocalZone
+}
+
+// ZoneOf returns the zone of the node, or empty when it is unknown.
+func (this *ZoneAffinitySelector) ZoneOf(node *ReplicaNode) string {
+	for _, one := range []string{node.Id, node.Addr, node.Hostname} {
+		if zone, isExists := this.NodeZones[one]; isExists && one != "" {
+			return zone
+		}
+	}
+
+	if node.Hostname == "" {
+		return ""
+	}
+	if this.ZoneFromHostname != nil {
+		return this.ZoneFromHostname(node.Hostname)
+	}
+	if this.hasZoneLabel(node.Hostname, this.LocalZone) {
+		return this.LocalZone
+	}
+	return ""
+}
+
+func (this *ZoneAffinitySelector) hasZoneLabel(hostname, zone string) bool {
+	if zone == "" {
+		return false
+	}
+	for _, label := range strings.Split(hostname, ".") {
+		if label == zone || strings.HasPrefix(label, zone+"-") || strings.HasSuffix(label, "-"+zone) {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	randLock   sync.Mutex
+	randHandle = rand.New(rand.NewSource(time.Now().UnixNano()))
+)
+
+// to get a random number by the shared source, it is safe for concurrent use.
+func randIntn(n int) int {
+	randLock.Lock()
+	defer randLock.Unlock()
+	return randHandle.Intn(n)
+}
@@ -0,0 +1,52 @@
+// Copyright (C) 2022
+// Author FrankXu <frankxury@gmail.com>
+// Build on 2026/10/18
+
+// The replica selectors test.
+
+package redis
+
+import (
+	goredis "github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestZoneAffinitySelector(t *testing.T) {
+	master := &ReplicaNode{Id: "aaaa", Addr: "10.0.0.1:6379", IsMaster: true}
+	replicaA := &ReplicaNode{Id: "bbbb", Addr: "10.0.0.2:6379", Hostname: "redis-1.us-east-1a.cache.internal"}
+	replicaB := &ReplicaNode{Id: "cccc", Addr: "10.0.0.3:6379", Hostname: "redis-2-us-east-1b"}
+
+	assert := assert.New(t)
+	selector := NewZoneAffinitySelector("us-east-1a", nil)
+	assert.Equal(replicaA, selector.Select(master, []*ReplicaNode{replicaA, replicaB}))
+	assert.Equal(replicaB, selector.Select(master, []*ReplicaNode{replicaB}))
+	assert.Equal(master, selector.Select(master, []*ReplicaNode{}))
+	assert.Nil(selector.Select(nil, []*ReplicaNode{}))
+
+	selector.FallbackToMaster = true
+	assert.Equal(master, selector.Select(master, []*ReplicaNode{replicaB}))
+
+	// the mapping wins over the hostname.
+	selector = NewZoneAffinitySelector("us-east-1b", map[string]string{"10.0.0.1:6379": "us-east-1b"})
+	assert.Equal(replicaB, selector.Select(master, []*ReplicaNode{replicaA, replicaB}))
+	assert.Equal(master, selector.Select(master, []*ReplicaNode{replicaA}))
+	assert.Equal("us-east-1b", selector.ZoneOf(master))
+	assert.Equal("", selector.ZoneOf(replicaA))
+}
+
+func TestSelectNodeByReplicaSelector(t *testing.T) {
+	assert := assert.New(t)
+	group := newTestGroup(t)
+	factory := NewRedisClientFactory(&goredis.ClusterOptions{ReadOnly: true})
+	factory.replicaSelector = NewZoneAffinitySelector("az1", map[string]string{"dddd": "az1", "cccc": "az1"})
+
+	for i := 0; i < 10; i++ {
+		node, err := factory.selectNode(group, READ_FROM_DEFAULT)
+		assert.Nil(err)
+		assert.Equal("dddd", node.Id)
+	}
+
+	node, _ := factory.selectNode(group, READ_FROM_MASTER)
+	assert.Equal("aaaa", node.Id)
+}
@@ -0,0 +1,240 @@
+// Copyright (C) 2022
+// Author FrankXu <frankxury@gmail.com>
+// Build on 2026/10/18
+
+// The set algebra across the shards.
+
+package redis
+
+import (
+	"context"
+	goredis "github.com/go-redis/redis/v8"
+	"sort"
+)
+
+const (
+	SET_OP_UNION = "sunion"
+	SET_OP_INTER = "sinter"
+	SET_OP_DIFF  = "sdiff"
+
+	// the sets larger are streamed by SSCAN instead of SMEMBERS, and the
+	// members stored are written by SADDs of this size.
+	SET_STREAM_SIZE = 10000
+)
+
+// SUnion gets the members of the union of the sets, across the slots.
+func (this *RedisCluster) SUnion(ctx context.Context, keys ...string) *goredis.StringSliceCmd {
+	return this.setAlgebra(ctx, SET_OP_UNION, keys)
+}
+
+// SInter gets the members of the intersection of the sets, across the slots.
+func (this *RedisCluster) SInter(ctx context.Context, keys ...string) *goredis.StringSliceCmd {
+	return this.setAlgebra(ctx, SET_OP_INTER, keys)
+}
+
+// SDiff gets the members of the first set not in the others, across the slots.
+func (this *RedisCluster) SDiff(ctx context.Context, keys ...string) *goredis.StringSliceCmd {
+	return this.setAlgebra(ctx, SET_OP_DIFF, keys)
+}
+
+// SUnionStore stores the union of the sets at the dest, and replies its size.
+func (this *RedisCluster) SUnionStore(ctx context.Context, dest string, keys ...string) *goredis.IntCmd {
+	return this.setAlgebraStore(ctx, SET_OP_UNION, dest, keys)
+}
+
+// SInterStore stores the intersection of the sets at the dest, and replies its size.
+func (this *RedisCluster) SInterStore(ctx context.Context, dest string, keys ...string) *goredis.IntCmd {
+	return this.setAlgebraStore(ctx, SET_OP_INTER, dest, keys)
+}
+
+// SDiffStore stores the difference of the sets at the dest, and replies its size.
+func (this *RedisCluster) SDiffStore(ctx context.Context, dest string, keys ...string) *goredis.IntCmd {
+	return this.setAlgebraStore(ctx, SET_OP_DIFF, dest, keys)
+}
+
+// to tell whether all the keys hash to one slot, the native command works then.
+func (this *RedisCluster) isSameSlot(keys ...string) bool {
+	crc16Handle := NewCRC16()
+	for _, key := range keys {
+		if crc16Handle.HashSlot(key) != crc16Handle.HashSlot(keys[0]) {
+			return false
+		}
+	}
+	return true
+}
+
+func (this *RedisCluster) setAlgebra(ctx context.Context, op string, keys []string) *goredis.StringSliceCmd {
+	if this.isSameSlot(keys...) {
+		switch op {
+		case SET_OP_UNION:
+			return this.ClusterClient.SUnion(ctx, keys...)
+		case SET_OP_INTER:
+			return this.ClusterClient.SInter(ctx, keys...)
+		default:
+			return this.ClusterClient.SDiff(ctx, keys...)
+		}
+	}
+
+	keyInfs := append([]interface{}{op}, this.strArr2InfArr(keys)...)
+	result := goredis.NewStringSliceCmd(ctx, keyInfs...)
+
+	members, err := this.computeSet(ctx, op, keys)
+	if err != nil {
+		result.SetErr(err)
+		return result
+	}
+
+	result.SetVal(members)
+	return result
+}
+
+func (this *RedisCluster) setAlgebraStore(ctx context.Context, op string, dest string, keys []string) *goredis.IntCmd {
+	if this.isSameSlot(append([]string{dest}, keys...)...) {
+		switch op {
+		case SET_OP_UNION:
+			return this.ClusterClient.SUnionStore(ctx, dest, keys...)
+		case SET_OP_INTER:
+			return this.ClusterClient.SInterStore(ctx, dest, keys...)
+		default:
+			return this.ClusterClient.SDiffStore(ctx, dest, keys...)
+		}
+	}
+
+	keyInfs := append([]interface{}{op + "store", dest}, this.strArr2InfArr(keys)...)
+	result := goredis.NewIntCmd(ctx, keyInfs...)
+
+	members, err := this.computeSet(ctx, op, keys)
+	if err == nil {
+		err = this.storeSet(ctx, dest, members)
+	}
+	if err != nil {
+		result.SetErr(err)
+		return result
+	}
+
+	result.SetVal(int64(len(members)))
+	return result
+}
+
+// computeSet counts the sets first, the small ones are fetched by SMEMBERS
+// in parallel per shard and the large ones are streamed by SSCAN, then the
+// sets are applied in order. The members are sorted.
+func (this *RedisCluster) computeSet(ctx context.Context, op string, keys []string) ([]string, error) {
+	cardRes := this.runBatch(ctx, keys, &batchSpec{
+		isWrite: false,
+		queue: queuePerKey(func(ctx context.Context, pipe goredis.Pipeliner, key string) goredis.Cmder {
+			return pipe.SCard(ctx, key)
+		}),
+	})
+	if err := cardRes.Err(); err != nil {
+		return nil, err
+	}
+
+	smallKeys := []string{}
+	for i, one := range cardRes.Results {
+		card, _ := one.Val.(int64)
+		// an empty set empties the intersection, or the difference when first.
+		if card == 0 && (op == SET_OP_INTER || (op == SET_OP_DIFF && i == 0)) {
+			return []string{}, nil
+		}
+		if card > 0 && card <= SET_STREAM_SIZE {
+			smallKeys = append(smallKeys, one.Key)
+		}
+	}
+
+	membersRes := this.runBatch(ctx, smallKeys, &batchSpec{
+		isWrite: false,
+		queue: queuePerKey(func(ctx context.Context, pipe goredis.Pipeliner, key string) goredis.Cmder {
+			return pipe.SMembers(ctx, key)
+		}),
+	})
+	if err := membersRes.Err(); err != nil {
+		return nil, err
+	}
+
+	memberMap := map[string]struct{}{}
+	for i, key := range keys {
+		card, _ := cardRes.Get(key).Val.(int64)
+		each := func(fn func(member string)) error {
+			if card > SET_STREAM_SIZE {
+				iter := this.ClusterClient.SScan(ctx, key, 0, "", SET_STREAM_SIZE).Iterator()
+				for iter.Next(ctx) {
+					fn(iter.Val())
+				}
+				return iter.Err()
+			}
+			if keyRes := membersRes.Get(key); keyRes != nil {
+				members, _ := keyRes.Val.([]string)
+				for _, member := range members {
+					fn(member)
+				}
+			}
+			return nil
+		}
+
+		var err error
+		if memberMap, err = applySetOp(op, i, memberMap, each); err != nil {
+			return nil, err
+		}
+		if len(memberMap) == 0 && op != SET_OP_UNION {
+			break
+		}
+	}
+
+	members := make([]string, 0, len(memberMap))
+	for member := range memberMap {
+		members = append(members, member)
+	}
+	sort.Strings(members)
+
+	return members, nil
+}
+
+// to apply the set of the index to the members, every member of the set is
+// given once or more to the fn of each.
+func applySetOp(op string, index int, memberMap map[string]struct{}, each func(fn func(member string)) error) (map[string]struct{}, error) {
+	switch {
+	case index == 0 || op == SET_OP_UNION:
+		return memberMap, each(func(member string) {
+			memberMap[member] = struct{}{}
+		})
+	case op == SET_OP_DIFF:
+		return memberMap, each(func(member string) {
+			delete(memberMap, member)
+		})
+	}
+
+	// the intersection keeps the members seen in the set.
+	seenMap := map[string]struct{}{}
+	err := each(func(member string) {
+		if _, isExists := memberMap[member]; isExists {
+			seenMap[member] = struct{}{}
+		}
+	})
+	return seenMap, err
+}
+
+// to write the members by SADDs of SET_STREAM_SIZE.
+func (this *RedisCluster) storeSet(ctx context.Context, dest string, members []string) error {
+	return this.replaceKey(ctx, dest, func(pipe goredis.Pipeliner) {
+		for start := 0; start < len(members); start += SET_STREAM_SIZE {
+			end := start + SET_STREAM_SIZE
+			if end > len(members) {
+				end = len(members)
+			}
+			pipe.SAdd(ctx, dest, this.strArr2InfArr(members[start:end])...)
+		}
+	})
+}
+
+// to replace the dest by the commands of the fill in one transaction on its
+// shard, the dest is deleted first so an empty result deletes it like the
+// native STOREs.
+func (this *RedisCluster) replaceKey(ctx context.Context, dest string, fill func(pipe goredis.Pipeliner)) error {
+	_, err := this.ClusterClient.TxPipelined(ctx, func(pipe goredis.Pipeliner) error {
+		pipe.Del(ctx, dest)
+		fill(pipe)
+		return nil
+	})
+	return err
+}
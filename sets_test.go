@@ -0,0 +1,88 @@
+// Copyright (C) 2022
+// Author FrankXu <frankxury@gmail.com>
+// Build on 2026/10/18
+
+// The set algebra test.
+
+package redis
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"strconv"
+	"testing"
+)
+
+func TestApplySetOp(t *testing.T) {
+	sets := [][]string{{"a", "b", "c", "d"}, {"b", "c", "e", "c"}, {"c", "f"}}
+	testCases := []struct {
+		Op      string
+		Members map[string]struct{}
+	}{
+		{SET_OP_UNION, map[string]struct{}{"a": {}, "b": {}, "c": {}, "d": {}, "e": {}, "f": {}}},
+		{SET_OP_INTER, map[string]struct{}{"c": {}}},
+		{SET_OP_DIFF, map[string]struct{}{"a": {}, "d": {}}},
+	}
+
+	assert := assert.New(t)
+	for _, test := range testCases {
+		memberMap := map[string]struct{}{}
+		for i, set := range sets {
+			var err error
+			memberMap, err = applySetOp(test.Op, i, memberMap, func(fn func(member string)) error {
+				for _, member := range set {
+					fn(member)
+				}
+				return nil
+			})
+			assert.Nil(err)
+		}
+		assert.Equal(test.Members, memberMap, "%s failed.", test.Op)
+	}
+}
+
+func TestSetAlgebra(t *testing.T) {
+	assert := assert.New(t)
+	cluster, fake := newFakeCluster(t, nil)
+	ctx := context.Background()
+
+	assert.True(cluster.isSameSlot("{u}a", "{u}b"))
+	assert.False(cluster.isSameSlot("a", "b"))
+
+	fake.Set("a", &fakeEntry{Type: "set", Set: map[string]bool{"1": true, "2": true, "3": true}})
+	fake.Set("b", &fakeEntry{Type: "set", Set: map[string]bool{"2": true, "3": true, "4": true}})
+	fake.Set("c", &fakeEntry{Type: "set", Set: map[string]bool{"3": true}})
+
+	cmd := cluster.SUnion(ctx, "a", "b", "missing")
+	assert.Nil(cmd.Err())
+	assert.Equal([]interface{}{"sunion", "a", "b", "missing"}, cmd.Args())
+	assert.Equal([]string{"1", "2", "3", "4"}, cmd.Val())
+	assert.Equal([]string{"2", "3"}, cluster.SInter(ctx, "a", "b").Val())
+	assert.Equal([]string{"1"}, cluster.SDiff(ctx, "a", "b", "c").Val())
+	assert.Equal([]string{}, cluster.SInter(ctx, "a", "b", "missing").Val())
+
+	// the dest is replaced, and deleted by an empty result.
+	fake.Set("d", &fakeEntry{Type: "string", Str: "old"})
+	storeCmd := cluster.SDiffStore(ctx, "d", "a", "c")
+	assert.Nil(storeCmd.Err())
+	assert.Equal([]interface{}{"sdiffstore", "d", "a", "c"}, storeCmd.Args())
+	assert.Equal(int64(2), storeCmd.Val())
+	assert.Equal(map[string]bool{"1": true, "2": true}, fake.Get("d").Set)
+
+	storeCmd = cluster.SInterStore(ctx, "d", "a", "missing")
+	assert.Nil(storeCmd.Err())
+	assert.Equal(int64(0), storeCmd.Val())
+	assert.Nil(fake.Get("d"))
+
+	// a large set is streamed by SSCAN.
+	large := map[string]bool{}
+	for i := 0; i <= SET_STREAM_SIZE; i++ {
+		large[strconv.Itoa(i+10)] = true
+	}
+	fake.Set("e", &fakeEntry{Type: "set", Set: large})
+	storeCmd = cluster.SUnionStore(ctx, "d", "a", "e")
+	assert.Nil(storeCmd.Err())
+	assert.Equal(int64(SET_STREAM_SIZE+4), storeCmd.Val())
+	assert.Len(fake.Get("d").Set, SET_STREAM_SIZE+4)
+	assert.Contains(fake.Cmds(), "sscan")
+}
@@ -0,0 +1,183 @@
+// Copyright (C) 2022
+// Author FrankXu <frankxury@gmail.com>
+// Build on 2026/10/18
+
+// The topology change events.
+
+package redis
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	EVENT_MASTER_FAILOVER = "master_failover"
+	EVENT_REPLICA_ADDED   = "replica_added"
+	EVENT_REPLICA_REMOVED = "replica_removed"
+	EVENT_SLOTS_MOVED     = "slots_moved"
+	EVENT_NODE_FAILED     = "node_failed"
+)
+
+// TopologyEvent is one change between two topology snapshots.
+type TopologyEvent struct {
+	Type   string
+	NodeId string
+	Addr   string
+
+	// the master of the replica, or the replaced master for a failover.
+	MasterId string
+
+	// the moved slot range, for the slots_moved event only.
+	StartSlot uint16
+	EndSlot   uint16
+	FromId    string
+
+	Time time.Time
+}
+
+type topologyNotifier struct {
+	lock      sync.Mutex
+	nextId    int
+	listeners map[int]chan *TopologyEvent
+}
+
+func newTopologyNotifier() *topologyNotifier {
+	return &topologyNotifier{listeners: map[int]chan *TopologyEvent{}}
+}
+
+func (this *topologyNotifier) subscribe(size int) (<-chan *TopologyEvent, func()) {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+
+	id := this.nextId
+	this.nextId++
+	ch := make(chan *TopologyEvent, size)
+	this.listeners[id] = ch
+
+	// the channel is closed by whoever removes it, the unsubscribe or the closeAll.
+	return ch, func() {
+		this.lock.Lock()
+		defer this.lock.Unlock()
+
+		if _, isExists := this.listeners[id]; isExists {
+			delete(this.listeners, id)
+			close(ch)
+		}
+	}
+}
+
+// to send the events without blocking, a full listener drops them.
+func (this *topologyNotifier) publish(events []*TopologyEvent) {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+
+	for _, event := range events {
+		for _, ch := range this.listeners {
+			select {
+			case ch <- event:
+			default:
+			}
+		}
+	}
+}
+
+func (this *topologyNotifier) closeAll() {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+
+	for id, ch := range this.listeners {
+		delete(this.listeners, id)
+		close(ch)
+	}
+}
+
+// diffSnapshots returns the changes from the old snapshot to the new one.
+func diffSnapshots(oldSnap, newSnap *nodesSnapshot) []*TopologyEvent {
+	events := []*TopologyEvent{}
+	if oldSnap == nil || newSnap == nil {
+		return events
+	}
+
+	now := time.Now()
+	_, oldSlaves := oldSnap.nodesByRole()
+	newMasters, newSlaves := newSnap.nodesByRole()
+
+	// the promoted replicas.
+	for id, node := range newMasters {
+		if oldSlave, isExists := oldSlaves[id]; isExists {
+			events = append(events, &TopologyEvent{Type: EVENT_MASTER_FAILOVER, NodeId: id, Addr: node.Addr(), MasterId: oldSlave.MasterId, Time: now})
+		}
+	}
+
+	// the replicas added or removed.
+	for id, node := range newSlaves {
+		if _, isExists := oldSlaves[id]; !isExists {
+			events = append(events, &TopologyEvent{Type: EVENT_REPLICA_ADDED, NodeId: id, Addr: node.Addr(), MasterId: node.MasterId, Time: now})
+		}
+	}
+	for id, node := range oldSlaves {
+		_, isSlave := newSlaves[id]
+		_, isPromoted := newMasters[id]
+		if !isSlave && !isPromoted {
+			events = append(events, &TopologyEvent{Type: EVENT_REPLICA_REMOVED, NodeId: id, Addr: node.Addr(), MasterId: node.MasterId, Time: now})
+		}
+	}
+
+	// the nodes newly marked as failed.
+	oldNodes := oldSnap.allNodes()
+	for id, node := range newSnap.allNodes() {
+		if !node.IsFailed() {
+			continue
+		}
+		if oldNode, isExists := oldNodes[id]; !isExists || !oldNode.IsFailed() {
+			events = append(events, &TopologyEvent{Type: EVENT_NODE_FAILED, NodeId: id, Addr: node.Addr(), MasterId: node.MasterId, Time: now})
+		}
+	}
+
+	// the moved slot ranges.
+	var cur *TopologyEvent
+	for slot := 0; slot < kClusterSlots; slot++ {
+		fromId, toId := oldSnap.slots[slot].masterId(), newSnap.slots[slot].masterId()
+		if fromId == toId || toId == "" {
+			cur = nil
+			continue
+		}
+		if cur != nil && cur.FromId == fromId && cur.NodeId == toId {
+			cur.EndSlot = uint16(slot)
+			continue
+		}
+		cur = &TopologyEvent{Type: EVENT_SLOTS_MOVED, NodeId: toId, Addr: newSnap.slots[slot].master.Addr(), FromId: fromId, StartSlot: uint16(slot), EndSlot: uint16(slot), Time: now}
+		events = append(events, cur)
+	}
+
+	return events
+}
+
+func (this *redisGroup) masterId() string {
+	if this == nil || this.master == nil {
+		return ""
+	}
+	return this.master.Id
+}
+
+func (this *nodesSnapshot) nodesByRole() (map[string]*redisNode, map[string]*redisNode) {
+	masters, slaves := map[string]*redisNode{}, map[string]*redisNode{}
+	for _, group := range this.groupMap {
+		if group.master != nil {
+			masters[group.master.Id] = group.master
+		}
+		for _, slave := range group.slaves {
+			slaves[slave.Id] = slave
+		}
+	}
+	return masters, slaves
+}
+
+func (this *nodesSnapshot) allNodes() map[string]*redisNode {
+	masters, slaves := this.nodesByRole()
+	for id, node := range slaves {
+		masters[id] = node
+	}
+	return masters
+}
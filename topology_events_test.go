@@ -0,0 +1,100 @@
+// Copyright (C) 2022
+// Author FrankXu <frankxury@gmail.com>
+// Build on 2026/10/18
+
+// The topology events test.
+
+package redis
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestDiffSnapshots(t *testing.T) {
+	oldInfo := "aaaa 10.0.0.1:6379@16379 master - 0 0 1 connected 0-8191\n" +
+		"bbbb 10.0.0.2:6379@16379 master - 0 0 2 connected 8192-16383\n" +
+		"cccc 10.0.0.3:6379@16379 slave aaaa 0 0 1 connected\n" +
+		"dddd 10.0.0.4:6379@16379 slave bbbb 0 0 2 connected\n"
+	newInfo := "aaaa 10.0.0.1:6379@16379 master,fail - 0 0 1 disconnected\n" +
+		"bbbb 10.0.0.2:6379@16379 master - 0 0 2 connected 8192-16383\n" +
+		"cccc 10.0.0.3:6379@16379 master - 0 0 3 connected 0-8191\n" +
+		"eeee 10.0.0.5:6379@16379 slave bbbb 0 0 2 connected\n"
+
+	assert := assert.New(t)
+	oldNodes, err := NewRedisNodes(oldInfo)
+	assert.Nil(err)
+	newNodes, err := NewRedisNodes(newInfo)
+	assert.Nil(err)
+
+	typeMap := map[string][]*TopologyEvent{}
+	for _, event := range diffSnapshots(oldNodes.Snapshot(), newNodes.Snapshot()) {
+		typeMap[event.Type] = append(typeMap[event.Type], event)
+	}
+
+	if assert.Len(typeMap[EVENT_MASTER_FAILOVER], 1) {
+		assert.Equal("cccc", typeMap[EVENT_MASTER_FAILOVER][0].NodeId)
+		assert.Equal("aaaa", typeMap[EVENT_MASTER_FAILOVER][0].MasterId)
+	}
+	if assert.Len(typeMap[EVENT_REPLICA_ADDED], 1) {
+		assert.Equal("eeee", typeMap[EVENT_REPLICA_ADDED][0].NodeId)
+	}
+	if assert.Len(typeMap[EVENT_REPLICA_REMOVED], 1) {
+		assert.Equal("dddd", typeMap[EVENT_REPLICA_REMOVED][0].NodeId)
+	}
+	if assert.Len(typeMap[EVENT_NODE_FAILED], 1) {
+		assert.Equal("aaaa", typeMap[EVENT_NODE_FAILED][0].NodeId)
+	}
+	if assert.Len(typeMap[EVENT_SLOTS_MOVED], 1) {
+		assert.Equal(TopologyEvent{Type: EVENT_SLOTS_MOVED, NodeId: "cccc", FromId: "aaaa", StartSlot: 0, EndSlot: 8191},
+			TopologyEvent{Type: typeMap[EVENT_SLOTS_MOVED][0].Type, NodeId: typeMap[EVENT_SLOTS_MOVED][0].NodeId, FromId: typeMap[EVENT_SLOTS_MOVED][0].FromId,
+				StartSlot: typeMap[EVENT_SLOTS_MOVED][0].StartSlot, EndSlot: typeMap[EVENT_SLOTS_MOVED][0].EndSlot})
+	}
+}
+
+func TestDiffSnapshotsAfterPatch(t *testing.T) {
+	assert := assert.New(t)
+	nodes, err := NewRedisNodes("aaaa 10.0.0.1:6379@16379 master - 0 0 1 connected 0-8191\n" +
+		"bbbb 10.0.0.2:6379@16379 master - 0 0 2 connected 8192-16383\n")
+	assert.Nil(err)
+	newNodes, err := NewRedisNodes("aaaa 10.0.0.1:6379@16379 master - 0 0 1 connected 0-8190\n" +
+		"bbbb 10.0.0.2:6379@16379 master - 0 0 2 connected 8192-16383\n" +
+		"cccc 10.0.0.3:6379@16379 master - 0 0 3 connected 8191\n")
+	assert.Nil(err)
+
+	// the slot learned by a MOVED to an unknown node is moved from its real owner.
+	nodes.PatchSlots(map[uint16]string{8191: "10.0.0.3:6379"})
+	events := diffSnapshots(nodes.LoadedSnapshot(), newNodes.Snapshot())
+	if assert.Len(events, 1) {
+		assert.Equal(EVENT_SLOTS_MOVED, events[0].Type)
+		assert.Equal("cccc", events[0].NodeId)
+		assert.Equal("aaaa", events[0].FromId)
+	}
+}
+
+func TestTopologyNotifier(t *testing.T) {
+	assert := assert.New(t)
+	notifier := newTopologyNotifier()
+	ch, unsubscribe := notifier.subscribe(1)
+
+	notifier.publish([]*TopologyEvent{{Type: EVENT_NODE_FAILED}, {Type: EVENT_REPLICA_ADDED}})
+	event := <-ch
+	assert.Equal(EVENT_NODE_FAILED, event.Type)
+
+	unsubscribe()
+	unsubscribe()
+	_, isOpen := <-ch
+	assert.False(isOpen)
+}
+
+func TestTopologyNotifierCloseAll(t *testing.T) {
+	assert := assert.New(t)
+	notifier := newTopologyNotifier()
+	ch, unsubscribe := notifier.subscribe(1)
+
+	// the unsubscribe deferred after the close does not close the channel again.
+	notifier.closeAll()
+	_, isOpen := <-ch
+	assert.False(isOpen)
+	assert.NotPanics(unsubscribe)
+}
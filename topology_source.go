@@ -0,0 +1,250 @@
+// Copyright (C) 2022
+// Author FrankXu <frankxury@gmail.com>
+// Build on 2026/10/18
+
+// The topology sources: CLUSTER SHARDS, CLUSTER NODES and CLUSTER SLOTS.
+
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	goredis "github.com/go-redis/redis/v8"
+	"strings"
+)
+
+const (
+	TOPOLOGY_SOURCE_AUTO   = "auto" // the first source supported by the server.
+	TOPOLOGY_SOURCE_SHARDS = "shards"
+	TOPOLOGY_SOURCE_NODES  = "nodes"
+	TOPOLOGY_SOURCE_SLOTS  = "slots"
+)
+
+// topologySource loads the nodes of the cluster.
+type topologySource interface {
+	Name() string
+	Load(ctx context.Context, client *goredis.ClusterClient) ([]*redisNode, error)
+}
+
+// the order tried by the auto selection, the richest first.
+var autoTopologySources = []topologySource{&shardsSource{}, &nodesSource{}, &slotsSource{}}
+
+func newTopologySource(name string) (topologySource, error) {
+	switch name {
+	case "", TOPOLOGY_SOURCE_AUTO:
+		return nil, nil
+	case TOPOLOGY_SOURCE_SHARDS:
+		return &shardsSource{}, nil
+	case TOPOLOGY_SOURCE_NODES:
+		return &nodesSource{}, nil
+	case TOPOLOGY_SOURCE_SLOTS:
+		return &slotsSource{}, nil
+	}
+	return nil, fmt.Errorf("unknown topology source: %s", name)
+}
+
+// loadTopology loads the nodes by the chosen source. With the auto selection
+// the sources are tried in order and the first one working is kept.
+func (this *RedisCluster) loadTopology(ctx context.Context) ([]*redisNode, error) {
+	if this.topologySource != nil {
+		return this.topologySource.Load(ctx, this.ClusterClient)
+	}
+
+	var lastErr error
+	for _, source := range autoTopologySources {
+		nodes, err := source.Load(ctx, this.ClusterClient)
+		if err == nil {
+			this.topologySource = source
+			return nodes, nil
+		}
+		lastErr = err
+	}
+
+	return nil, lastErr
+}
+
+type nodesSource struct{}
+
+func (this *nodesSource) Name() string {
+	return TOPOLOGY_SOURCE_NODES
+}
+
+func (this *nodesSource) Load(ctx context.Context, client *goredis.ClusterClient) ([]*redisNode, error) {
+	info, err := client.ClusterNodes(ctx).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	nodes := []*redisNode{}
+	for _, nodeInfo := range strings.Split(info, "\n") {
+		if nodeInfo == "" {
+			continue
+		}
+		node, err := NewRedisNode(nodeInfo)
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, node)
+	}
+
+	return nodes, nil
+}
+
+type slotsSource struct{}
+
+func (this *slotsSource) Name() string {
+	return TOPOLOGY_SOURCE_SLOTS
+}
+
+// CLUSTER SLOTS lists the master first and then its replicas for every range.
+func (this *slotsSource) Load(ctx context.Context, client *goredis.ClusterClient) ([]*redisNode, error) {
+	slots, err := client.ClusterSlots(ctx).Result()
+	if err != nil {
+		return nil, err
+	}
+	return this.parse(slots), nil
+}
+
+func (this *slotsSource) parse(slots []goredis.ClusterSlot) []*redisNode {
+	nodeMap := map[string]*redisNode{}
+	nodes := []*redisNode{}
+	for _, slot := range slots {
+		if len(slot.Nodes) == 0 || slot.Start < 0 || slot.End >= kClusterSlots {
+			continue
+		}
+
+		masterId := ""
+		for i, one := range slot.Nodes {
+			id := one.ID
+			if id == "" {
+				id = one.Addr
+			}
+
+			node, isExists := nodeMap[id]
+			if !isExists {
+				node = &redisNode{Id: id, Role: ROLE_SLAVE, MasterId: masterId, LinkState: LINK_CONNECTED}
+				node.Ip, node.Port = node.getAddr(one.Addr)
+				if i == 0 {
+					node.Role = ROLE_MASTER
+				}
+				node.Flags = []string{node.Role}
+				nodeMap[id] = node
+				nodes = append(nodes, node)
+			}
+
+			if i == 0 {
+				masterId = id
+				node.addSlotArea(uint16(slot.Start), uint16(slot.End))
+			}
+		}
+	}
+
+	return nodes
+}
+
+type shardsSource struct{}
+
+func (this *shardsSource) Name() string {
+	return TOPOLOGY_SOURCE_SHARDS
+}
+
+// for examples, every shard is a flat key value array:
+// ["slots", [0, 5460], "nodes", [["id", "e10b7051...", "port", 30001, "ip", "127.0.0.1",
+// "endpoint", "127.0.0.1", "hostname", "", "role", "master", "replication-offset", 72156, "health", "online"]]]
+func (this *shardsSource) Load(ctx context.Context, client *goredis.ClusterClient) ([]*redisNode, error) {
+	res, err := client.Do(ctx, "cluster", "shards").Slice()
+	if err != nil {
+		return nil, err
+	}
+	return this.parse(res)
+}
+
+func (this *shardsSource) parse(res []interface{}) ([]*redisNode, error) {
+	nodes := []*redisNode{}
+	for _, shardInf := range res {
+		shard, err := this.toMap(shardInf)
+		if err != nil {
+			return nil, err
+		}
+
+		shardNodes := []*redisNode{}
+		var master *redisNode
+		nodeInfs, _ := shard["nodes"].([]interface{})
+		for _, nodeInf := range nodeInfs {
+			info, err := this.toMap(nodeInf)
+			if err != nil {
+				return nil, err
+			}
+
+			node := &redisNode{
+				Id:        this.toString(info["id"]),
+				Ip:        this.toString(info["ip"]),
+				Port:      this.toString(info["port"]),
+				TlsPort:   this.toString(info["tls-port"]),
+				Hostname:  this.toString(info["hostname"]),
+				Role:      ROLE_SLAVE,
+				LinkState: LINK_CONNECTED,
+			}
+			if node.Ip == "" {
+				node.Ip = this.toString(info["endpoint"])
+			}
+			if this.toString(info["role"]) == ROLE_MASTER {
+				node.Role = ROLE_MASTER
+				master = node
+			}
+			node.Flags = []string{node.Role}
+			if this.toString(info["health"]) == "fail" || this.toString(info["health"]) == "failed" {
+				node.Flags = append(node.Flags, FLAG_FAIL)
+				node.LinkState = "disconnected"
+			}
+			shardNodes = append(shardNodes, node)
+		}
+
+		if master == nil {
+			continue
+		}
+
+		slotInfs, _ := shard["slots"].([]interface{})
+		for i := 0; i+1 < len(slotInfs); i += 2 {
+			startSlot, isStartOk := slotInfs[i].(int64)
+			endSlot, isEndOk := slotInfs[i+1].(int64)
+			if isStartOk && isEndOk && startSlot >= 0 && endSlot < kClusterSlots {
+				master.addSlotArea(uint16(startSlot), uint16(endSlot))
+			}
+		}
+
+		for _, node := range shardNodes {
+			if node != master {
+				node.MasterId = master.Id
+			}
+		}
+		nodes = append(nodes, shardNodes...)
+	}
+
+	return nodes, nil
+}
+
+// to convert the flat key value array to a map.
+func (this *shardsSource) toMap(inf interface{}) (map[string]interface{}, error) {
+	arr, isOk := inf.([]interface{})
+	if !isOk || len(arr)%2 != 0 {
+		return nil, errors.New("cluster shards reply error")
+	}
+
+	res := map[string]interface{}{}
+	for i := 0; i < len(arr); i += 2 {
+		res[this.toString(arr[i])] = arr[i+1]
+	}
+	return res, nil
+}
+
+func (this *shardsSource) toString(inf interface{}) string {
+	switch val := inf.(type) {
+	case string:
+		return val
+	case int64:
+		return fmt.Sprintf("%d", val)
+	}
+	return ""
+}
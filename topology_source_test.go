@@ -0,0 +1,75 @@
+// Copyright (C) 2022
+// Author FrankXu <frankxury@gmail.com>
+// Build on 2026/10/18
+
+// The topology sources test.
+
+package redis
+
+import (
+	goredis "github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestShardsSourceParse(t *testing.T) {
+	res := []interface{}{
+		[]interface{}{
+			"slots", []interface{}{int64(0), int64(5460), int64(5462), int64(5462)},
+			"nodes", []interface{}{
+				[]interface{}{"id", "aaaa", "port", int64(6379), "ip", "10.0.0.1", "endpoint", "10.0.0.1", "hostname", "redis-a", "role", "master", "replication-offset", int64(72156), "health", "online"},
+				[]interface{}{"id", "bbbb", "port", int64(6379), "tls-port", int64(6380), "ip", "10.0.0.2", "endpoint", "10.0.0.2", "hostname", "", "role", "replica", "replication-offset", int64(72156), "health", "failed"},
+			},
+		},
+	}
+
+	assert := assert.New(t)
+	nodes, err := (&shardsSource{}).parse(res)
+	assert.Nil(err)
+	assert.Len(nodes, 2)
+
+	assert.Equal(ROLE_MASTER, nodes[0].Role)
+	assert.Equal("10.0.0.1:6379", nodes[0].Addr())
+	assert.Equal("redis-a", nodes[0].Hostname)
+	assert.Equal([]*slotArea{{0, 5460}, {5462, 5462}}, nodes[0].SlotAreas)
+
+	assert.Equal(ROLE_SLAVE, nodes[1].Role)
+	assert.Equal("aaaa", nodes[1].MasterId)
+	assert.Equal("6380", nodes[1].TlsPort)
+	assert.False(nodes[1].IsAvailable())
+
+	_, err = (&shardsSource{}).parse([]interface{}{"slots"})
+	assert.NotNil(err)
+}
+
+func TestSlotsSourceParse(t *testing.T) {
+	slots := []goredis.ClusterSlot{
+		{Start: 0, End: 8191, Nodes: []goredis.ClusterNode{{ID: "aaaa", Addr: "10.0.0.1:6379"}, {ID: "cccc", Addr: "10.0.0.3:6379"}}},
+		{Start: 8192, End: 16383, Nodes: []goredis.ClusterNode{{ID: "bbbb", Addr: "10.0.0.2:6379"}}},
+	}
+
+	assert := assert.New(t)
+	redisNodes := &redisNodes{}
+	redisNodes.SetNodes((&slotsSource{}).parse(slots))
+
+	group, isFound := redisNodes.FindNodeByCRC16Val(100)
+	assert.True(isFound)
+	assert.Equal("aaaa", group.master.Id)
+	if assert.Len(group.slaves, 1) {
+		assert.Equal("10.0.0.3:6379", group.slaves[0].Addr())
+	}
+	group, _ = redisNodes.FindNodeByCRC16Val(16383)
+	assert.Equal("bbbb", group.master.Id)
+}
+
+func TestNewTopologySource(t *testing.T) {
+	assert := assert.New(t)
+	source, err := newTopologySource("")
+	assert.Nil(err)
+	assert.Nil(source)
+	source, err = newTopologySource(TOPOLOGY_SOURCE_SHARDS)
+	assert.Nil(err)
+	assert.Equal(TOPOLOGY_SOURCE_SHARDS, source.Name())
+	_, err = newTopologySource("gossip")
+	assert.NotNil(err)
+}
@@ -0,0 +1,270 @@
+// Copyright (C) 2022
+// Author FrankXu <frankxury@gmail.com>
+// Build on 2026/10/18
+
+// The sorted set aggregation across the shards.
+
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	goredis "github.com/go-redis/redis/v8"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	ZSET_OP_UNION = "zunion"
+	ZSET_OP_INTER = "zinter"
+
+	AGGREGATE_SUM = "sum"
+	AGGREGATE_MIN = "min"
+	AGGREGATE_MAX = "max"
+)
+
+// ZUnionStore stores the union of the sorted sets at the dest, across the slots.
+func (this *RedisCluster) ZUnionStore(ctx context.Context, dest string, store *goredis.ZStore) *goredis.IntCmd {
+	return this.ZUnionStoreWithTTL(ctx, dest, store, 0)
+}
+
+// ZUnionStoreWithTTL stores the union like ZUnionStore, the dest expires
+// after the ttl when it is positive.
+func (this *RedisCluster) ZUnionStoreWithTTL(ctx context.Context, dest string, store *goredis.ZStore, ttl time.Duration) *goredis.IntCmd {
+	return this.zAggregateStore(ctx, ZSET_OP_UNION, dest, store, ttl)
+}
+
+// ZInterStore stores the intersection of the sorted sets at the dest, across the slots.
+func (this *RedisCluster) ZInterStore(ctx context.Context, dest string, store *goredis.ZStore) *goredis.IntCmd {
+	return this.ZInterStoreWithTTL(ctx, dest, store, 0)
+}
+
+// ZInterStoreWithTTL stores the intersection like ZInterStore, the dest
+// expires after the ttl when it is positive.
+func (this *RedisCluster) ZInterStoreWithTTL(ctx context.Context, dest string, store *goredis.ZStore, ttl time.Duration) *goredis.IntCmd {
+	return this.zAggregateStore(ctx, ZSET_OP_INTER, dest, store, ttl)
+}
+
+// ZUnion gets the members of the union of the sorted sets, across the slots.
+func (this *RedisCluster) ZUnion(ctx context.Context, store goredis.ZStore) *goredis.StringSliceCmd {
+	return this.toMembersCmd(ctx, this.ZUnionWithScores(ctx, store))
+}
+
+// ZUnionWithScores gets the union of the sorted sets with the scores, across the slots.
+func (this *RedisCluster) ZUnionWithScores(ctx context.Context, store goredis.ZStore) *goredis.ZSliceCmd {
+	if this.isSameSlot(store.Keys...) {
+		return this.ClusterClient.ZUnionWithScores(ctx, store)
+	}
+	return this.zAggregate(ctx, ZSET_OP_UNION, &store)
+}
+
+// ZInter gets the members of the intersection of the sorted sets, across the slots.
+func (this *RedisCluster) ZInter(ctx context.Context, store *goredis.ZStore) *goredis.StringSliceCmd {
+	return this.toMembersCmd(ctx, this.ZInterWithScores(ctx, store))
+}
+
+// ZInterWithScores gets the intersection of the sorted sets with the scores, across the slots.
+func (this *RedisCluster) ZInterWithScores(ctx context.Context, store *goredis.ZStore) *goredis.ZSliceCmd {
+	if this.isSameSlot(store.Keys...) {
+		return this.ClusterClient.ZInterWithScores(ctx, store)
+	}
+	return this.zAggregate(ctx, ZSET_OP_INTER, store)
+}
+
+// to keep the members only.
+func (this *RedisCluster) toMembersCmd(ctx context.Context, zCmd *goredis.ZSliceCmd) *goredis.StringSliceCmd {
+	result := goredis.NewStringSliceCmd(ctx, zCmd.Args()...)
+	if err := zCmd.Err(); err != nil {
+		result.SetErr(err)
+		return result
+	}
+
+	members := make([]string, 0, len(zCmd.Val()))
+	for _, one := range zCmd.Val() {
+		members = append(members, fmt.Sprint(one.Member))
+	}
+	result.SetVal(members)
+	return result
+}
+
+func (this *RedisCluster) zAggregate(ctx context.Context, op string, store *goredis.ZStore) *goredis.ZSliceCmd {
+	keyInfs := append([]interface{}{op, len(store.Keys)}, this.strArr2InfArr(store.Keys)...)
+	result := goredis.NewZSliceCmd(ctx, keyInfs...)
+
+	zs, err := this.computeZSet(ctx, op, store)
+	if err != nil {
+		result.SetErr(err)
+		return result
+	}
+
+	result.SetVal(zs)
+	return result
+}
+
+func (this *RedisCluster) zAggregateStore(ctx context.Context, op string, dest string, store *goredis.ZStore, ttl time.Duration) *goredis.IntCmd {
+	// the native command, in one transaction with the ttl.
+	if this.isSameSlot(append([]string{dest}, store.Keys...)...) {
+		if ttl <= 0 && op == ZSET_OP_UNION {
+			return this.ClusterClient.ZUnionStore(ctx, dest, store)
+		} else if ttl <= 0 {
+			return this.ClusterClient.ZInterStore(ctx, dest, store)
+		}
+
+		var storeCmd *goredis.IntCmd
+		_, err := this.ClusterClient.TxPipelined(ctx, func(pipe goredis.Pipeliner) error {
+			if op == ZSET_OP_UNION {
+				storeCmd = pipe.ZUnionStore(ctx, dest, store)
+			} else {
+				storeCmd = pipe.ZInterStore(ctx, dest, store)
+			}
+			pipe.PExpire(ctx, dest, ttl)
+			return nil
+		})
+		if err != nil && storeCmd.Err() == nil {
+			storeCmd.SetErr(err)
+		}
+		return storeCmd
+	}
+
+	keyInfs := append([]interface{}{op + "store", dest, len(store.Keys)}, this.strArr2InfArr(store.Keys)...)
+	result := goredis.NewIntCmd(ctx, keyInfs...)
+
+	zs, err := this.computeZSet(ctx, op, store)
+	if err == nil {
+		err = this.storeZSet(ctx, dest, zs, ttl)
+	}
+	if err != nil {
+		result.SetErr(err)
+		return result
+	}
+
+	result.SetVal(int64(len(zs)))
+	return result
+}
+
+// to fetch the members with the scores of every sorted set in parallel per
+// shard, and aggregate them.
+func (this *RedisCluster) computeZSet(ctx context.Context, op string, store *goredis.ZStore) ([]goredis.Z, error) {
+	if len(store.Weights) > 0 && len(store.Weights) != len(store.Keys) {
+		return nil, errors.New("The weights should be as many as the keys.")
+	}
+
+	batchRes := this.runBatch(ctx, store.Keys, &batchSpec{
+		isWrite: false,
+		queue: queuePerKey(func(ctx context.Context, pipe goredis.Pipeliner, key string) goredis.Cmder {
+			return pipe.ZRangeWithScores(ctx, key, 0, -1)
+		}),
+	})
+	if err := batchRes.Err(); err != nil {
+		return nil, err
+	}
+
+	sets := make([][]goredis.Z, 0, len(store.Keys))
+	for _, one := range batchRes.Results {
+		zs, _ := one.Val.([]goredis.Z)
+		sets = append(sets, zs)
+	}
+
+	return aggregateZSets(op, sets, store.Weights, store.Aggregate)
+}
+
+// aggregateZSets merges the sorted sets like ZUNION and ZINTER, the scores
+// are multiplied by the weights and aggregated by SUM, MIN or MAX. The
+// result is sorted by the score and then the member, like redis.
+func aggregateZSets(op string, sets [][]goredis.Z, weights []float64, aggregate string) ([]goredis.Z, error) {
+	aggregate = strings.ToLower(aggregate)
+	switch aggregate {
+	case "":
+		aggregate = AGGREGATE_SUM
+	case AGGREGATE_SUM, AGGREGATE_MIN, AGGREGATE_MAX:
+	default:
+		return nil, fmt.Errorf("unknown aggregate: %s", aggregate)
+	}
+
+	scoreMap := map[string]float64{}
+	for i, set := range sets {
+		weight := 1.0
+		if i < len(weights) {
+			weight = weights[i]
+		}
+
+		seenMap := make(map[string]float64, len(set))
+		for _, one := range set {
+			member := fmt.Sprint(one.Member)
+			// 0 * inf is 0 in redis, not NaN.
+			score := 0.0
+			if one.Score != 0 && weight != 0 {
+				score = one.Score * weight
+			}
+
+			oldScore, isExists := scoreMap[member]
+			switch {
+			case i == 0 || (op == ZSET_OP_UNION && !isExists):
+				seenMap[member] = score
+			case !isExists:
+				// not in the intersection.
+			default:
+				seenMap[member] = aggregateScore(aggregate, oldScore, score)
+			}
+		}
+
+		if i == 0 || op == ZSET_OP_INTER {
+			scoreMap = seenMap
+		} else {
+			for member, score := range seenMap {
+				scoreMap[member] = score
+			}
+		}
+	}
+
+	zs := make([]goredis.Z, 0, len(scoreMap))
+	for member, score := range scoreMap {
+		zs = append(zs, goredis.Z{Score: score, Member: member})
+	}
+	sort.Slice(zs, func(i, j int) bool {
+		if zs[i].Score != zs[j].Score {
+			return zs[i].Score < zs[j].Score
+		}
+		return zs[i].Member.(string) < zs[j].Member.(string)
+	})
+
+	return zs, nil
+}
+
+func aggregateScore(aggregate string, oldScore, score float64) float64 {
+	switch aggregate {
+	case AGGREGATE_MIN:
+		return math.Min(oldScore, score)
+	case AGGREGATE_MAX:
+		return math.Max(oldScore, score)
+	}
+
+	// inf + -inf is 0 in redis, not NaN.
+	if sum := oldScore + score; !math.IsNaN(sum) {
+		return sum
+	}
+	return 0
+}
+
+// to write the members by ZADDs of SET_STREAM_SIZE, with the ttl.
+func (this *RedisCluster) storeZSet(ctx context.Context, dest string, zs []goredis.Z, ttl time.Duration) error {
+	return this.replaceKey(ctx, dest, func(pipe goredis.Pipeliner) {
+		for start := 0; start < len(zs); start += SET_STREAM_SIZE {
+			end := start + SET_STREAM_SIZE
+			if end > len(zs) {
+				end = len(zs)
+			}
+			members := make([]*goredis.Z, 0, end-start)
+			for i := start; i < end; i++ {
+				members = append(members, &zs[i])
+			}
+			pipe.ZAdd(ctx, dest, members...)
+		}
+		if ttl > 0 && len(zs) > 0 {
+			pipe.PExpire(ctx, dest, ttl)
+		}
+	})
+}
@@ -0,0 +1,91 @@
+// Copyright (C) 2022
+// Author FrankXu <frankxury@gmail.com>
+// Build on 2026/10/18
+
+// The sorted set aggregation test.
+
+package redis
+
+import (
+	"context"
+	goredis "github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestAggregateZSets(t *testing.T) {
+	sets := [][]goredis.Z{
+		{{Score: 1, Member: "a"}, {Score: 2, Member: "b"}, {Score: 3, Member: "c"}},
+		{{Score: 10, Member: "b"}, {Score: 20, Member: "c"}, {Score: 30, Member: "d"}},
+	}
+	testCases := []struct {
+		Op        string
+		Weights   []float64
+		Aggregate string
+		Zs        []goredis.Z
+	}{
+		{ZSET_OP_UNION, nil, "", []goredis.Z{{Score: 1, Member: "a"}, {Score: 12, Member: "b"}, {Score: 23, Member: "c"}, {Score: 30, Member: "d"}}},
+		{ZSET_OP_UNION, []float64{2, 0.5}, "MAX", []goredis.Z{{Score: 2, Member: "a"}, {Score: 5, Member: "b"}, {Score: 10, Member: "c"}, {Score: 15, Member: "d"}}},
+		{ZSET_OP_INTER, nil, "min", []goredis.Z{{Score: 2, Member: "b"}, {Score: 3, Member: "c"}}},
+		{ZSET_OP_INTER, []float64{1, -1}, "sum", []goredis.Z{{Score: -17, Member: "c"}, {Score: -8, Member: "b"}}},
+	}
+
+	assert := assert.New(t)
+	for _, test := range testCases {
+		zs, err := aggregateZSets(test.Op, sets, test.Weights, test.Aggregate)
+		assert.Nil(err)
+		assert.Equal(test.Zs, zs, "%s %v %s failed.", test.Op, test.Weights, test.Aggregate)
+	}
+
+	_, err := aggregateZSets(ZSET_OP_UNION, sets, nil, "avg")
+	assert.NotNil(err)
+
+	// like redis, the NaN scores are 0.
+	zs, _ := aggregateZSets(ZSET_OP_UNION, [][]goredis.Z{{{Score: math.Inf(1), Member: "a"}}, {{Score: math.Inf(-1), Member: "a"}}}, nil, "")
+	assert.Equal([]goredis.Z{{Score: 0, Member: "a"}}, zs)
+	zs, _ = aggregateZSets(ZSET_OP_UNION, [][]goredis.Z{{{Score: math.Inf(1), Member: "a"}}}, []float64{0}, "")
+	assert.Equal([]goredis.Z{{Score: 0, Member: "a"}}, zs)
+}
+
+func TestZAggregate(t *testing.T) {
+	assert := assert.New(t)
+	cluster := newTestCluster(t, nil)
+	ctx := context.Background()
+
+	cmd := cluster.ZUnionStore(ctx, "c", &goredis.ZStore{Keys: []string{"a", "b"}, Weights: []float64{1}})
+	assert.Equal("The weights should be as many as the keys.", cmd.Err().Error())
+}
+
+func TestZAggregateOnFake(t *testing.T) {
+	assert := assert.New(t)
+	cluster, fake := newFakeCluster(t, nil)
+	ctx := context.Background()
+
+	fake.Set("a", &fakeEntry{Type: "zset", ZSet: map[string]float64{"x": 1, "y": 2}})
+	fake.Set("b", &fakeEntry{Type: "zset", ZSet: map[string]float64{"y": 10, "z": 0.5}})
+
+	membersCmd := cluster.ZInter(ctx, &goredis.ZStore{Keys: []string{"a", "b"}})
+	assert.Nil(membersCmd.Err())
+	assert.Equal([]interface{}{"zinter", 2, "a", "b"}, membersCmd.Args())
+	assert.Equal([]string{"y"}, membersCmd.Val())
+	assert.Equal([]string{"z", "x", "y"}, cluster.ZUnion(ctx, goredis.ZStore{Keys: []string{"a", "b"}}).Val())
+
+	zs, err := cluster.ZUnionWithScores(ctx, goredis.ZStore{Keys: []string{"a", "b"}, Aggregate: "max"}).Result()
+	assert.Nil(err)
+	assert.Equal([]goredis.Z{{Score: 0.5, Member: "z"}, {Score: 1, Member: "x"}, {Score: 10, Member: "y"}}, zs)
+
+	// the dest is replaced with the ttl, and deleted by an empty result.
+	fake.Set("c", &fakeEntry{Type: "zset", ZSet: map[string]float64{"old": 1}})
+	cmd := cluster.ZUnionStoreWithTTL(ctx, "c", &goredis.ZStore{Keys: []string{"a", "b"}, Weights: []float64{2, 1}}, time.Minute)
+	assert.Nil(cmd.Err())
+	assert.Equal(int64(3), cmd.Val())
+	assert.Equal(map[string]float64{"x": 2, "y": 14, "z": 0.5}, fake.Get("c").ZSet)
+	assert.True(fake.Get("c").ExpireAt > 0)
+
+	cmd = cluster.ZInterStore(ctx, "c", &goredis.ZStore{Keys: []string{"a", "missing"}})
+	assert.Nil(cmd.Err())
+	assert.Equal(int64(0), cmd.Val())
+	assert.Nil(fake.Get("c"))
+}